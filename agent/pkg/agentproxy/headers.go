@@ -3,25 +3,106 @@ package agentproxy
 import (
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+
+	gphttp "github.com/yusing/go-proxy/internal/net/gphttp"
 )
 
 const (
 	HeaderXProxyHost                  = "X-Proxy-Host"
 	HeaderXProxyHTTPS                 = "X-Proxy-Https"
 	HeaderXProxySkipTLSVerify         = "X-Proxy-Skip-Tls-Verify"
+	HeaderXProxySSLServerName         = "X-Proxy-Ssl-Server-Name"
+	HeaderXProxySSLCA                 = "X-Proxy-Ssl-Ca"
+	HeaderXProxySSLCert               = "X-Proxy-Ssl-Cert"
+	HeaderXProxySSLKey                = "X-Proxy-Ssl-Key"
+	HeaderXProxySSLProtocols          = "X-Proxy-Ssl-Protocols"
 	HeaderXProxyResponseHeaderTimeout = "X-Proxy-Response-Header-Timeout"
+
+	HeaderXProxyDialTimeout         = "X-Proxy-Dial-Timeout"
+	HeaderXProxyKeepAlive           = "X-Proxy-Keep-Alive"
+	HeaderXProxyDisableKeepAlives   = "X-Proxy-Disable-Keep-Alives"
+	HeaderXProxyMaxIdleConns        = "X-Proxy-Max-Idle-Conns"
+	HeaderXProxyMaxIdleConnsPerHost = "X-Proxy-Max-Idle-Conns-Per-Host"
+	HeaderXProxyMaxConnsPerHost     = "X-Proxy-Max-Conns-Per-Host"
+	HeaderXProxyIdleConnTimeout     = "X-Proxy-Idle-Conn-Timeout"
+	HeaderXProxyHTTPVersion         = "X-Proxy-Http-Version"
 )
 
 type AgentProxyHeaders struct {
-	Host                  string
-	IsHTTPS               bool
-	SkipTLSVerify         bool
+	Host          string
+	IsHTTPS       bool
+	SkipTLSVerify bool
+	// SSLCA, SSLCert and SSLKey are paths on the agent host.
+	SSLServerName         string
+	SSLCA                 string
+	SSLCert               string
+	SSLKey                string
+	SSLProtocols          []string
 	ResponseHeaderTimeout int
+	TransportOptions      gphttp.TransportOptions
 }
 
 func SetAgentProxyHeaders(r *http.Request, headers *AgentProxyHeaders) {
 	r.Header.Set(HeaderXProxyHost, headers.Host)
 	r.Header.Set(HeaderXProxyHTTPS, strconv.FormatBool(headers.IsHTTPS))
 	r.Header.Set(HeaderXProxySkipTLSVerify, strconv.FormatBool(headers.SkipTLSVerify))
+	r.Header.Set(HeaderXProxySSLServerName, headers.SSLServerName)
+	r.Header.Set(HeaderXProxySSLCA, headers.SSLCA)
+	r.Header.Set(HeaderXProxySSLCert, headers.SSLCert)
+	r.Header.Set(HeaderXProxySSLKey, headers.SSLKey)
+	r.Header.Set(HeaderXProxySSLProtocols, strings.Join(headers.SSLProtocols, ","))
 	r.Header.Set(HeaderXProxyResponseHeaderTimeout, strconv.Itoa(headers.ResponseHeaderTimeout))
+
+	opts := &headers.TransportOptions
+	r.Header.Set(HeaderXProxyDialTimeout, opts.DialTimeout.String())
+	r.Header.Set(HeaderXProxyKeepAlive, opts.KeepAlive.String())
+	r.Header.Set(HeaderXProxyDisableKeepAlives, strconv.FormatBool(opts.DisableKeepAlives))
+	r.Header.Set(HeaderXProxyMaxIdleConns, strconv.Itoa(opts.MaxIdleConns))
+	r.Header.Set(HeaderXProxyMaxIdleConnsPerHost, strconv.Itoa(opts.MaxIdleConnsPerHost))
+	r.Header.Set(HeaderXProxyMaxConnsPerHost, strconv.Itoa(opts.MaxConnsPerHost))
+	r.Header.Set(HeaderXProxyIdleConnTimeout, opts.IdleConnTimeout.String())
+	r.Header.Set(HeaderXProxyHTTPVersion, opts.HTTPVersion)
+}
+
+// DelAgentProxyHeaders removes the headers set by SetAgentProxyHeaders,
+// so they are not forwarded to the upstream.
+func DelAgentProxyHeaders(h http.Header) {
+	for _, k := range []string{
+		HeaderXProxyHost,
+		HeaderXProxyHTTPS,
+		HeaderXProxySkipTLSVerify,
+		HeaderXProxySSLServerName,
+		HeaderXProxySSLCA,
+		HeaderXProxySSLCert,
+		HeaderXProxySSLKey,
+		HeaderXProxySSLProtocols,
+		HeaderXProxyResponseHeaderTimeout,
+		HeaderXProxyDialTimeout,
+		HeaderXProxyKeepAlive,
+		HeaderXProxyDisableKeepAlives,
+		HeaderXProxyMaxIdleConns,
+		HeaderXProxyMaxIdleConnsPerHost,
+		HeaderXProxyMaxConnsPerHost,
+		HeaderXProxyIdleConnTimeout,
+		HeaderXProxyHTTPVersion,
+	} {
+		h.Del(k)
+	}
+}
+
+// GetTransportOptions returns the transport options set by SetAgentProxyHeaders,
+// invalid or missing values are left as zero.
+func GetTransportOptions(h http.Header) *gphttp.TransportOptions {
+	opts := new(gphttp.TransportOptions)
+	opts.DialTimeout, _ = time.ParseDuration(h.Get(HeaderXProxyDialTimeout))
+	opts.KeepAlive, _ = time.ParseDuration(h.Get(HeaderXProxyKeepAlive))
+	opts.DisableKeepAlives, _ = strconv.ParseBool(h.Get(HeaderXProxyDisableKeepAlives))
+	opts.MaxIdleConns, _ = strconv.Atoi(h.Get(HeaderXProxyMaxIdleConns))
+	opts.MaxIdleConnsPerHost, _ = strconv.Atoi(h.Get(HeaderXProxyMaxIdleConnsPerHost))
+	opts.MaxConnsPerHost, _ = strconv.Atoi(h.Get(HeaderXProxyMaxConnsPerHost))
+	opts.IdleConnTimeout, _ = time.ParseDuration(h.Get(HeaderXProxyIdleConnTimeout))
+	opts.HTTPVersion = h.Get(HeaderXProxyHTTPVersion)
+	return opts
 }
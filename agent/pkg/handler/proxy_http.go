@@ -1,14 +1,16 @@
 package handler
 
 import (
-	"crypto/tls"
 	"net/http"
 	"net/http/httputil"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/yusing/go-proxy/agent/pkg/agent"
 	"github.com/yusing/go-proxy/agent/pkg/agentproxy"
+	gphttp "github.com/yusing/go-proxy/internal/net/gphttp"
 )
 
 func NewTransport() *http.Transport {
@@ -23,14 +25,98 @@ func NewTransport() *http.Transport {
 	}
 }
 
+// transports caches transports by the options of the request,
+// so connections to upstreams are reused across requests.
+//
+// It holds at most maxTransports transports, an arbitrary one is evicted
+// when it is full, since the options come from request headers.
+var (
+	transports   = make(map[string]*http.Transport)
+	transportsMu sync.RWMutex
+)
+
+const maxTransports = 64
+
+// transportHeaders are the headers of transport options.
+var transportHeaders = []string{
+	agentproxy.HeaderXProxySkipTLSVerify,
+	agentproxy.HeaderXProxySSLServerName,
+	agentproxy.HeaderXProxySSLCA,
+	agentproxy.HeaderXProxySSLCert,
+	agentproxy.HeaderXProxySSLKey,
+	agentproxy.HeaderXProxySSLProtocols,
+	agentproxy.HeaderXProxyResponseHeaderTimeout,
+	agentproxy.HeaderXProxyDialTimeout,
+	agentproxy.HeaderXProxyKeepAlive,
+	agentproxy.HeaderXProxyDisableKeepAlives,
+	agentproxy.HeaderXProxyMaxIdleConns,
+	agentproxy.HeaderXProxyMaxIdleConnsPerHost,
+	agentproxy.HeaderXProxyMaxConnsPerHost,
+	agentproxy.HeaderXProxyIdleConnTimeout,
+	agentproxy.HeaderXProxyHTTPVersion,
+}
+
+func getTransport(h http.Header) (*http.Transport, error) {
+	var key strings.Builder
+	for _, k := range transportHeaders {
+		key.WriteString(h.Get(k))
+		key.WriteByte(0)
+	}
+	transportsMu.RLock()
+	tr, ok := transports[key.String()]
+	transportsMu.RUnlock()
+	if ok {
+		return tr, nil
+	}
+
+	skipTLSVerify, _ := strconv.ParseBool(h.Get(agentproxy.HeaderXProxySkipTLSVerify))
+	tlsOpts := &gphttp.UpstreamTLSOptions{
+		SkipVerify: skipTLSVerify,
+		ServerName: h.Get(agentproxy.HeaderXProxySSLServerName),
+		CA:         h.Get(agentproxy.HeaderXProxySSLCA),
+		Cert:       h.Get(agentproxy.HeaderXProxySSLCert),
+		Key:        h.Get(agentproxy.HeaderXProxySSLKey),
+	}
+	if protocols := h.Get(agentproxy.HeaderXProxySSLProtocols); protocols != "" {
+		tlsOpts.Protocols = strings.Split(protocols, ",")
+	}
+	transOpts := agentproxy.GetTransportOptions(h)
+	if err := transOpts.Validate(); err != nil {
+		return nil, err
+	}
+
+	transport := NewTransport()
+	if !tlsOpts.IsZero() {
+		tlsConfig, err := tlsOpts.TLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	if responseHeaderTimeout, _ := strconv.Atoi(h.Get(agentproxy.HeaderXProxyResponseHeaderTimeout)); responseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = time.Duration(responseHeaderTimeout) * time.Second
+	}
+	transOpts.Apply(transport)
+
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	if tr, ok := transports[key.String()]; ok {
+		return tr, nil
+	}
+	if len(transports) >= maxTransports {
+		for k, tr := range transports {
+			tr.CloseIdleConnections()
+			delete(transports, k)
+			break
+		}
+	}
+	transports[key.String()] = transport
+	return transport, nil
+}
+
 func ProxyHTTP(w http.ResponseWriter, r *http.Request) {
 	host := r.Header.Get(agentproxy.HeaderXProxyHost)
 	isHTTPS, _ := strconv.ParseBool(r.Header.Get(agentproxy.HeaderXProxyHTTPS))
-	skipTLSVerify, _ := strconv.ParseBool(r.Header.Get(agentproxy.HeaderXProxySkipTLSVerify))
-	responseHeaderTimeout, err := strconv.Atoi(r.Header.Get(agentproxy.HeaderXProxyResponseHeaderTimeout))
-	if err != nil {
-		responseHeaderTimeout = 0
-	}
 
 	if host == "" {
 		http.Error(w, "missing required headers", http.StatusBadRequest)
@@ -42,14 +128,12 @@ func ProxyHTTP(w http.ResponseWriter, r *http.Request) {
 		scheme = "https"
 	}
 
-	transport := NewTransport()
-	if skipTLSVerify {
-		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-	}
-
-	if responseHeaderTimeout > 0 {
-		transport.ResponseHeaderTimeout = time.Duration(responseHeaderTimeout) * time.Second
+	transport, err := getTransport(r.Header)
+	if err != nil {
+		http.Error(w, "invalid transport config: "+err.Error(), http.StatusBadRequest)
+		return
 	}
+	agentproxy.DelAgentProxyHeaders(r.Header)
 
 	r.URL.Scheme = ""
 	r.URL.Host = ""
@@ -15,7 +15,6 @@ import (
 	"github.com/yusing/go-proxy/internal/logging/memlogger"
 	"github.com/yusing/go-proxy/internal/metrics/systeminfo"
 	"github.com/yusing/go-proxy/internal/metrics/uptime"
-	"github.com/yusing/go-proxy/internal/net/gphttp/middleware"
 	"github.com/yusing/go-proxy/internal/task"
 	"github.com/yusing/go-proxy/pkg"
 )
@@ -42,7 +41,6 @@ func main() {
 		dnsproviders.InitProviders,
 		homepage.InitIconListCache,
 		systeminfo.Poller.Start,
-		middleware.LoadComposeFiles,
 	)
 
 	if common.APIJWTSecret == nil {
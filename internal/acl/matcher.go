@@ -2,6 +2,7 @@ package acl
 
 import (
 	"net"
+	"slices"
 	"strings"
 
 	"github.com/yusing/go-proxy/internal/gperr"
@@ -11,6 +12,7 @@ import (
 type MatcherFunc func(*maxmind.IPInfo) bool
 
 type Matcher struct {
+	typ   string
 	match MatcherFunc
 }
 
@@ -67,9 +69,18 @@ func (matcher *Matcher) Parse(s string) error {
 	default:
 		return errSyntax
 	}
+	matcher.typ = parts[0]
 	return nil
 }
 
+// RequiresMaxMind returns whether any of the matchers looks up the MaxMind database,
+// i.e. a `tz` or `country` matcher.
+func (matchers Matchers) RequiresMaxMind() bool {
+	return slices.ContainsFunc(matchers, func(m Matcher) bool {
+		return m.typ == MatcherTypeTimeZone || m.typ == MatcherTypeCountry
+	})
+}
+
 func (matchers Matchers) Match(ip *maxmind.IPInfo) bool {
 	for _, m := range matchers {
 		if m.match(ip) {
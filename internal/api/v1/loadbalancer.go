@@ -0,0 +1,131 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/yusing/go-proxy/internal/net/gphttp"
+	"github.com/yusing/go-proxy/internal/net/gphttp/gpwebsocket"
+	"github.com/yusing/go-proxy/internal/net/gphttp/httpheaders"
+	"github.com/yusing/go-proxy/internal/net/gphttp/loadbalancer"
+	loadbalance "github.com/yusing/go-proxy/internal/net/gphttp/loadbalancer/types"
+	"github.com/yusing/go-proxy/internal/route/routes"
+)
+
+type (
+	loadBalancerStats struct {
+		Name    string                                     `json:"name"`
+		Mode    loadbalance.Mode                           `json:"mode"`
+		Status  string                                     `json:"status"`
+		Servers []loadBalancerServerStats                  `json:"servers"`
+		Zones   map[string]loadbalance.ServerStatsSnapshot `json:"zones"`
+		// Variants is the traffic statistics by variant, to compare error rates during rollout.
+		Variants map[string]loadBalancerVariantStats `json:"variants"`
+	}
+	loadBalancerVariantStats struct {
+		Weight loadbalance.Weight              `json:"weight"`
+		Stats  loadbalance.ServerStatsSnapshot `json:"stats"`
+	}
+	loadBalancerServerStats struct {
+		Name            string                          `json:"name"`
+		URL             string                          `json:"url"`
+		Weight          loadbalance.Weight              `json:"weight"`
+		EffectiveWeight loadbalance.Weight              `json:"effective_weight"`
+		Priority        loadbalance.Priority            `json:"priority"`
+		Zone            string                          `json:"zone"`
+		Variant         string                          `json:"variant"`
+		Status          string                          `json:"status"`
+		Ejected         bool                            `json:"ejected"`
+		Stats           loadbalance.ServerStatsSnapshot `json:"stats"`
+	}
+)
+
+func LoadBalancer(w http.ResponseWriter, r *http.Request) {
+	lb, ok := getLoadBalancer(w, r)
+	if !ok {
+		return
+	}
+	if httpheaders.IsWebsocket(r.Header) {
+		gpwebsocket.Periodic(w, r, 1*time.Second, func(conn *websocket.Conn) error {
+			return conn.WriteJSON(getLoadBalancerStats(lb))
+		})
+	} else {
+		gphttp.RespondJSON(w, r, getLoadBalancerStats(lb))
+	}
+}
+
+// SetLoadBalancerWeights sets the share of weights of each variant of a load balancer,
+// e.g. `{"stable": 90, "canary": 10}`.
+func SetLoadBalancerWeights(w http.ResponseWriter, r *http.Request) {
+	lb, ok := getLoadBalancer(w, r)
+	if !ok {
+		return
+	}
+	var weights map[string]loadbalance.Weight
+	if err := json.NewDecoder(r.Body).Decode(&weights); err != nil {
+		gphttp.ClientError(w, r, err, http.StatusBadRequest)
+		return
+	}
+	if err := lb.SetVariantWeights(weights); err != nil {
+		gphttp.ClientError(w, r, err, http.StatusBadRequest)
+		return
+	}
+	gphttp.RespondJSON(w, r, getLoadBalancerStats(lb))
+}
+
+// getLoadBalancer returns the load balancer of the HTTP or stream route named by the alias path value.
+func getLoadBalancer(w http.ResponseWriter, r *http.Request) (*loadbalancer.LoadBalancer, bool) {
+	alias := r.PathValue("alias")
+	route, ok := routes.Get(alias) // HTTP or stream
+	if !ok {
+		gphttp.ValueNotFound(w, "route", alias)
+		return nil, false
+	}
+	lb, ok := route.HealthMonitor().(*loadbalancer.LoadBalancer)
+	if !ok {
+		gphttp.BadRequest(w, "route "+alias+" is not a load balancer")
+		return nil, false
+	}
+	return lb, true
+}
+
+func getLoadBalancerStats(lb *loadbalancer.LoadBalancer) loadBalancerStats {
+	srvs := lb.Servers()
+	slices.SortFunc(srvs, func(a, b loadbalance.Server) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	stats := loadBalancerStats{
+		Name:     lb.Name(),
+		Mode:     lb.Mode,
+		Status:   lb.Status().String(),
+		Servers:  make([]loadBalancerServerStats, len(srvs)),
+		Zones:    lb.ZoneStats(),
+		Variants: make(map[string]loadBalancerVariantStats),
+	}
+	variantStats := lb.VariantStats()
+	for variant, weight := range lb.Variants() {
+		stats.Variants[variant] = loadBalancerVariantStats{
+			Weight: weight,
+			Stats:  variantStats[variant],
+		}
+	}
+	for i, srv := range srvs {
+		stats.Servers[i] = loadBalancerServerStats{
+			Name:            srv.Name(),
+			URL:             srv.URL().String(),
+			Weight:          srv.Weight(),
+			EffectiveWeight: srv.EffectiveWeight(),
+			Priority:        srv.Priority(),
+			Zone:            srv.Zone(),
+			Variant:         srv.Variant(),
+			Status:          srv.Status().String(),
+			Ejected:         lb.IsEjected(srv),
+			Stats:           srv.Stats().Snapshot(),
+		}
+	}
+	return stats
+}
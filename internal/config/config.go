@@ -18,6 +18,7 @@ import (
 	"github.com/yusing/go-proxy/internal/entrypoint"
 	"github.com/yusing/go-proxy/internal/gperr"
 	"github.com/yusing/go-proxy/internal/maxmind"
+	"github.com/yusing/go-proxy/internal/net/gphttp/middleware"
 	"github.com/yusing/go-proxy/internal/net/gphttp/server"
 	"github.com/yusing/go-proxy/internal/notif"
 	"github.com/yusing/go-proxy/internal/proxmox"
@@ -42,6 +43,9 @@ type Config struct {
 var (
 	cfgWatcher watcher.Watcher
 	reloadMu   sync.Mutex
+
+	// compose files are loaded once, after MaxMind is initialized by the first load.
+	loadMiddlewareComposeFilesOnce sync.Once
 )
 
 const configEventFlushInterval = 500 * time.Millisecond
@@ -202,6 +206,10 @@ func (cfg *Config) StartServers(opts ...*StartServersOptions) {
 			HTTPSAddr:    common.ProxyHTTPSAddr,
 			Handler:      cfg.entrypoint,
 			ACL:          cfg.value.ACL,
+
+			TLSPassthrough: cfg.entrypoint.TLSPassthrough,
+			ProxyProtocol:  cfg.value.Entrypoint.ProxyProtocol,
+			H2C:            cfg.value.Entrypoint.H2C,
 		})
 	}
 	if opt.API {
@@ -229,21 +237,26 @@ func (cfg *Config) load() gperr.Error {
 
 	// errors are non fatal below
 	errs := gperr.NewBuilder(errMsg)
+	// middlewares with geo matchers require MaxMind on finalize
+	errs.Add(cfg.initMaxMind(model.Providers.MaxMind))
+	loadMiddlewareComposeFilesOnce.Do(middleware.LoadComposeFiles)
 	errs.Add(cfg.entrypoint.SetMiddlewares(model.Entrypoint.Middlewares))
+	errs.Add(cfg.entrypoint.SetRequestID(model.Entrypoint.RequestID))
 	errs.Add(cfg.entrypoint.SetAccessLogger(cfg.task, model.Entrypoint.AccessLog))
-	errs.Add(cfg.initMaxMind(model.Providers.MaxMind))
 	cfg.initNotification(model.Providers.Notification)
 	errs.Add(cfg.initAutoCert(model.AutoCert))
 	errs.Add(cfg.initProxmox(model.Providers.Proxmox))
-	errs.Add(cfg.loadRouteProviders(&model.Providers))
 
-	cfg.value = model
 	for i, domain := range model.MatchDomains {
 		if !strings.HasPrefix(domain, ".") {
 			model.MatchDomains[i] = "." + domain
 		}
 	}
+	// set before loading routes, hosts of routes are checked against aliases by the domains
 	cfg.entrypoint.SetFindRouteDomains(model.MatchDomains)
+	errs.Add(cfg.loadRouteProviders(&model.Providers))
+
+	cfg.value = model
 	if model.ACL.Valid() {
 		err := model.ACL.Start(cfg.task)
 		if err != nil {
@@ -12,6 +12,7 @@ import (
 	"github.com/yusing/go-proxy/internal/gperr"
 	"github.com/yusing/go-proxy/internal/logging/accesslog"
 	maxmind "github.com/yusing/go-proxy/internal/maxmind/types"
+	"github.com/yusing/go-proxy/internal/net/proxyproto"
 	"github.com/yusing/go-proxy/internal/notif"
 	"github.com/yusing/go-proxy/internal/proxmox"
 	"github.com/yusing/go-proxy/internal/serialization"
@@ -38,6 +39,12 @@ type (
 	Entrypoint struct {
 		Middlewares []map[string]any               `json:"middlewares"`
 		AccessLog   *accesslog.RequestLoggerConfig `json:"access_log" validate:"omitempty"`
+		// RequestID assigns `X-Request-ID` and W3C trace context headers to every request
+		RequestID bool `json:"request_id"`
+		// ProxyProtocol accepts PROXY protocol headers from trusted sources on the HTTP and HTTPS ports
+		ProxyProtocol *proxyproto.Config `json:"proxy_protocol" validate:"omitempty"`
+		// H2C accepts HTTP/2 without TLS (h2c with prior knowledge) on the HTTP port, e.g. from gRPC clients
+		H2C bool `json:"h2c"`
 	}
 	HomepageConfig struct {
 		UseDefaultCategories bool `json:"use_default_categories"`
@@ -1,8 +1,10 @@
 package entrypoint
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
 
@@ -18,6 +20,7 @@ import (
 
 type Entrypoint struct {
 	middleware    *middleware.Middleware
+	requestID     *middleware.Middleware
 	accessLogger  *accesslog.AccessLogger
 	findRouteFunc func(host string) (routes.HTTPRoute, error)
 }
@@ -31,6 +34,7 @@ func NewEntrypoint() *Entrypoint {
 }
 
 func (ep *Entrypoint) SetFindRouteDomains(domains []string) {
+	routes.SetMatchDomains(domains)
 	if len(domains) == 0 {
 		ep.findRouteFunc = findRouteAnyDomain
 	} else {
@@ -54,6 +58,22 @@ func (ep *Entrypoint) SetMiddlewares(mws []map[string]any) error {
 	return nil
 }
 
+func (ep *Entrypoint) SetRequestID(enabled bool) error {
+	if !enabled {
+		ep.requestID = nil
+		return nil
+	}
+
+	mid, err := middleware.RequestID.New(nil)
+	if err != nil {
+		return err
+	}
+	ep.requestID = mid
+
+	log.Debug().Msg("entrypoint request id enabled")
+	return nil
+}
+
 func (ep *Entrypoint) SetAccessLogger(parent task.Parent, cfg *accesslog.RequestLoggerConfig) (err error) {
 	if cfg == nil {
 		ep.accessLogger = nil
@@ -69,6 +89,14 @@ func (ep *Entrypoint) SetAccessLogger(parent task.Parent, cfg *accesslog.Request
 }
 
 func (ep *Entrypoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if ep.requestID != nil {
+		ep.requestID.ServeHTTP(ep.serveHTTP, w, r)
+		return
+	}
+	ep.serveHTTP(w, r)
+}
+
+func (ep *Entrypoint) serveHTTP(w http.ResponseWriter, r *http.Request) {
 	mux, err := ep.findRouteFunc(r.Host)
 	if err == nil {
 		if ep.accessLogger != nil {
@@ -96,6 +124,7 @@ func (ep *Entrypoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			Msg("request")
 		errorPage, ok := errorpage.GetErrorPageByStatus(http.StatusNotFound)
 		if ok {
+			errorPage = errorpage.WithRequestID(errorPage, r)
 			w.WriteHeader(http.StatusNotFound)
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
 			if _, err := w.Write(errorPage); err != nil {
@@ -107,18 +136,51 @@ func (ep *Entrypoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// TLSPassthrough returns the handler of TLS connections with the server name
+// if it belongs to a TLS passthrough route, or nil otherwise.
+func (ep *Entrypoint) TLSPassthrough(serverName string) func(conn net.Conn) {
+	r, err := ep.findRouteFunc(serverName)
+	if err != nil {
+		return nil
+	}
+	route, ok := r.(routes.TLSPassthroughRoute)
+	if !ok {
+		return nil
+	}
+	return func(conn net.Conn) {
+		if err := route.HandleTLSConn(conn); err != nil && !errors.Is(err, context.Canceled) {
+			log.Err(err).
+				Str("route", route.Name()).
+				Str("remote", conn.RemoteAddr().String()).
+				Msg("tls passthrough")
+		}
+	}
+}
+
 func findRouteAnyDomain(host string) (routes.HTTPRoute, error) {
+	if r, ok := routes.GetHTTPRouteByHost(host); ok {
+		return r, nil
+	}
+
 	hostSplit := strutils.SplitRune(host, '.')
 	target := hostSplit[0]
 
 	if r, ok := routes.GetHTTPRouteOrExact(target, host); ok {
 		return r, nil
 	}
+	if r, ok := routes.GetHTTPRouteByWildcard(host); ok {
+		return r, nil
+	}
 	return nil, fmt.Errorf("%w: %s", ErrNoSuchRoute, target)
 }
 
 func findRouteByDomains(domains []string) func(host string) (routes.HTTPRoute, error) {
 	return func(host string) (routes.HTTPRoute, error) {
+		// explicit hosts of routes take precedence over aliases
+		if r, ok := routes.GetHTTPRouteByHost(host); ok {
+			return r, nil
+		}
+
 		for _, domain := range domains {
 			if strings.HasSuffix(host, domain) {
 				target := strings.TrimSuffix(host, domain)
@@ -132,6 +194,9 @@ func findRouteByDomains(domains []string) func(host string) (routes.HTTPRoute, e
 		if r, ok := routes.HTTP.Get(host); ok {
 			return r, nil
 		}
+		if r, ok := routes.GetHTTPRouteByWildcard(host); ok {
+			return r, nil
+		}
 		return nil, fmt.Errorf("%w: %s", ErrNoSuchRoute, host)
 	}
 }
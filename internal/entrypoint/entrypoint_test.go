@@ -125,3 +125,85 @@ func TestFindRouteByDomainsExactMatch(t *testing.T) {
 
 	run(t, tests, testsNoMatch)
 }
+
+func addRouteWithHosts(t *testing.T, alias string, hosts ...string) {
+	t.Helper()
+	r := &route.ReveseProxyRoute{
+		Route: &route.Route{
+			Alias: alias,
+			Hosts: hosts,
+		},
+	}
+	routes.HTTP.Add(r)
+	expect.NoError(t, routes.AddHosts(r, hosts))
+}
+
+func TestFindRouteByHosts(t *testing.T) {
+	addRouteWithHosts(t, "app1", "app1.example.org", "*.tenant.example.com")
+	addRouteWithHosts(t, "app2", "*.sub.tenant.example.com")
+
+	tests := []string{
+		"app1.example.org",
+		"app1.example.org:8443",
+		"a.tenant.example.com",
+		"a.b.tenant.example.com",
+		"a.sub.tenant.example.com",
+	}
+	testsNoMatch := []string{
+		"tenant.example.com",
+		"example.org",
+		"sub.app1.example.org",
+		"a.tenant.example.org",
+	}
+
+	run(t, tests, testsNoMatch)
+}
+
+func TestFindRouteByWildcardMostSpecific(t *testing.T) {
+	t.Cleanup(routes.Clear)
+
+	addRouteWithHosts(t, "app1", "*.tenant.example.com")
+	addRouteWithHosts(t, "app2", "*.sub.tenant.example.com")
+
+	found, err := ep.findRouteFunc("a.sub.tenant.example.com")
+	expect.NoError(t, err)
+	expect.Equal(t, found.Name(), "app2")
+
+	found, err = ep.findRouteFunc("a.other.tenant.example.com")
+	expect.NoError(t, err)
+	expect.Equal(t, found.Name(), "app1")
+}
+
+func TestAddHostsConflict(t *testing.T) {
+	t.Cleanup(routes.Clear)
+
+	addRouteWithHosts(t, "app1", "app.example.com")
+	r := &route.ReveseProxyRoute{
+		Route: &route.Route{
+			Alias: "app2",
+		},
+	}
+	expect.ErrorContains(t, routes.AddHosts(r, []string{"app.example.com"}), "already exists")
+}
+
+func TestAddHostsAliasConflict(t *testing.T) {
+	t.Cleanup(routes.Clear)
+	t.Cleanup(func() { ep.SetFindRouteDomains(nil) })
+
+	addRoute("app")
+	r := &route.ReveseProxyRoute{
+		Route: &route.Route{
+			Alias: "other",
+		},
+	}
+	expect.ErrorContains(t, routes.AddHosts(r, []string{"app.example.com"}), "alias of route app")
+	_, ok := routes.GetHTTPRouteByHost("app.example.com")
+	expect.False(t, ok)
+
+	ep.SetFindRouteDomains([]string{".example.com"})
+	expect.ErrorContains(t, routes.AddHosts(r, []string{"app.example.com"}), "alias of route app")
+	// not resolved to the alias by the match domains
+	expect.NoError(t, routes.AddHosts(r, []string{"app.example.org"}))
+	// wildcards are looked up after aliases
+	expect.NoError(t, routes.AddHosts(r, []string{"*.example.com"}))
+}
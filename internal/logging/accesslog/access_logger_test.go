@@ -9,6 +9,7 @@ import (
 	"time"
 
 	. "github.com/yusing/go-proxy/internal/logging/accesslog"
+	"github.com/yusing/go-proxy/internal/net/gphttp/httpheaders"
 	"github.com/yusing/go-proxy/internal/task"
 	"github.com/yusing/go-proxy/internal/utils"
 	expect "github.com/yusing/go-proxy/internal/utils/testing"
@@ -113,6 +114,8 @@ type JSONLogEntry struct {
 	Query       map[string][]string `json:"query,omitempty"`
 	Headers     map[string][]string `json:"headers,omitempty"`
 	Cookies     map[string]string   `json:"cookies,omitempty"`
+	RequestID   string              `json:"request_id,omitempty"`
+	TraceID     string              `json:"trace_id,omitempty"`
 }
 
 func getJSONEntry(t *testing.T, config *RequestLoggerConfig) JSONLogEntry {
@@ -146,6 +149,37 @@ func TestAccessLoggerJSON(t *testing.T) {
 	}
 }
 
+func TestAccessLoggerJSONRequestID(t *testing.T) {
+	entry := getJSONEntry(t, DefaultRequestLoggerConfig())
+	expect.Equal(t, entry.RequestID, "")
+	expect.Equal(t, entry.TraceID, "")
+
+	const (
+		requestID = "c0ffee"
+		traceID   = "4bf92f3577b34da6a3ce929d0e0e4736"
+	)
+	req := req.Clone(t.Context())
+	req.Header.Set("X-Request-ID", requestID)
+	req.Header.Set("Traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+
+	config := DefaultRequestLoggerConfig()
+	config.Format = FormatJSON
+	logger := NewMockAccessLogger(testTask, config)
+	var traced JSONLogEntry
+	err := json.Unmarshal(logger.AppendRequestLog(nil, req, resp), &traced)
+	expect.NoError(t, err)
+	expect.Equal(t, traced.RequestID, requestID)
+	expect.Equal(t, traced.TraceID, traceID)
+
+	// set by the request_id middleware with a custom header
+	req = httpheaders.WithRequestID(req.Clone(t.Context()), "custom-id")
+	req.Header.Del("X-Request-ID")
+	var custom JSONLogEntry
+	err = json.Unmarshal(logger.AppendRequestLog(nil, req, resp), &custom)
+	expect.NoError(t, err)
+	expect.Equal(t, custom.RequestID, "custom-id")
+}
+
 func BenchmarkAccessLoggerJSON(b *testing.B) {
 	config := DefaultRequestLoggerConfig()
 	config.Format = FormatJSON
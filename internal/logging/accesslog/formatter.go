@@ -9,6 +9,7 @@ import (
 
 	"github.com/rs/zerolog"
 	maxmind "github.com/yusing/go-proxy/internal/maxmind/types"
+	"github.com/yusing/go-proxy/internal/net/gphttp/httpheaders"
 	"github.com/yusing/go-proxy/internal/utils"
 )
 
@@ -119,6 +120,13 @@ func (f *JSONFormatter) AppendRequestLog(line []byte, req *http.Request, res *ht
 		Object("headers", headers).
 		Object("cookies", cookies)
 
+	if requestID := httpheaders.RequestID(req); requestID != "" {
+		event.Str("request_id", requestID)
+	}
+	if traceID := httpheaders.TraceID(req.Header); traceID != "" {
+		event.Str("trace_id", traceID)
+	}
+
 	if res.StatusCode >= 400 {
 		if res.Status != "" {
 			event.Str("error", res.Status)
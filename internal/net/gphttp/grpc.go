@@ -0,0 +1,84 @@
+package gphttp
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/yusing/go-proxy/internal/net/gphttp/httpheaders"
+)
+
+// gRPC status codes used by GoDoxy.
+//
+// https://grpc.github.io/grpc/core/md_doc_statuscodes.html
+const (
+	GRPCStatusOK               = 0
+	GRPCStatusUnknown          = 2
+	GRPCStatusDeadlineExceeded = 4
+	GRPCStatusPermissionDenied = 7
+	GRPCStatusUnimplemented    = 12
+	GRPCStatusInternal         = 13
+	GRPCStatusUnavailable      = 14
+	GRPCStatusUnauthenticated  = 16
+)
+
+// GRPCStatusFromHTTP maps a HTTP status code of a non-gRPC response to a gRPC status code.
+//
+// https://github.com/grpc/grpc/blob/master/doc/http-grpc-status-mapping.md
+func GRPCStatusFromHTTP(status int) int {
+	switch status {
+	case http.StatusBadRequest:
+		return GRPCStatusInternal
+	case http.StatusUnauthorized:
+		return GRPCStatusUnauthenticated
+	case http.StatusForbidden:
+		return GRPCStatusPermissionDenied
+	case http.StatusNotFound:
+		return GRPCStatusUnimplemented
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return GRPCStatusUnavailable
+	}
+	return GRPCStatusUnknown
+}
+
+// GRPCErrorHeader returns the header of a trailers-only gRPC response with the status code and message.
+func GRPCErrorHeader(code int, msg string) http.Header {
+	h := make(http.Header, 3)
+	h.Set("Content-Type", "application/grpc")
+	h.Set(httpheaders.HeaderGRPCStatus, strconv.Itoa(code))
+	if msg != "" {
+		h.Set(httpheaders.HeaderGRPCMessage, encodeGRPCMessage(msg))
+	}
+	return h
+}
+
+// GRPCError responds with a trailers-only gRPC response with the status code and message.
+//
+// gRPC clients ignore the HTTP status code of a gRPC response, so http.StatusOK is always used.
+func GRPCError(w http.ResponseWriter, code int, msg string) {
+	h := w.Header()
+	for k, v := range GRPCErrorHeader(code, msg) {
+		h[k] = v
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// encodeGRPCMessage percent-encodes the message as required by the gRPC over HTTP/2 spec.
+func encodeGRPCMessage(msg string) string {
+	const hex = "0123456789ABCDEF"
+	var sb strings.Builder
+	for i := range len(msg) {
+		c := msg[i]
+		if c >= ' ' && c <= '~' && c != '%' {
+			sb.WriteByte(c)
+			continue
+		}
+		sb.WriteByte('%')
+		sb.WriteByte(hex[c>>4])
+		sb.WriteByte(hex[c&0xf])
+	}
+	return sb.String()
+}
@@ -0,0 +1,25 @@
+package gphttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/yusing/go-proxy/internal/utils/testing"
+)
+
+func TestGRPCStatusFromHTTP(t *testing.T) {
+	ExpectEqual(t, GRPCStatusFromHTTP(http.StatusNotFound), GRPCStatusUnimplemented)
+	ExpectEqual(t, GRPCStatusFromHTTP(http.StatusServiceUnavailable), GRPCStatusUnavailable)
+	ExpectEqual(t, GRPCStatusFromHTTP(http.StatusUnauthorized), GRPCStatusUnauthenticated)
+	ExpectEqual(t, GRPCStatusFromHTTP(http.StatusTeapot), GRPCStatusUnknown)
+}
+
+func TestGRPCError(t *testing.T) {
+	w := httptest.NewRecorder()
+	GRPCError(w, GRPCStatusUnavailable, "no server available: 100%")
+	ExpectEqual(t, w.Code, http.StatusOK)
+	ExpectEqual(t, w.Header().Get("Content-Type"), "application/grpc")
+	ExpectEqual(t, w.Header().Get("Grpc-Status"), "14")
+	ExpectEqual(t, w.Header().Get("Grpc-Message"), "no server available: 100%25")
+}
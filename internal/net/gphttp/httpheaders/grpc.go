@@ -0,0 +1,16 @@
+package httpheaders
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	HeaderGRPCStatus  = "Grpc-Status"
+	HeaderGRPCMessage = "Grpc-Message"
+)
+
+// IsGRPC returns whether the content type is gRPC, e.g. `application/grpc` or `application/grpc+proto`.
+func IsGRPC(h http.Header) bool {
+	return strings.HasPrefix(h.Get("Content-Type"), "application/grpc")
+}
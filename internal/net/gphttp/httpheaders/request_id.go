@@ -0,0 +1,22 @@
+package httpheaders
+
+import (
+	"context"
+	"net/http"
+)
+
+type requestIDContextKey struct{}
+
+// WithRequestID returns a shallow copy of r with the request ID stored in its context.
+func WithRequestID(r *http.Request, id string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), requestIDContextKey{}, id))
+}
+
+// RequestID returns the request ID stored by WithRequestID,
+// or the `X-Request-ID` header if there is none.
+func RequestID(r *http.Request) string {
+	if id, ok := r.Context().Value(requestIDContextKey{}).(string); ok {
+		return id
+	}
+	return r.Header.Get(HeaderXRequestID)
+}
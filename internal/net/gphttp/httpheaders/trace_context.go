@@ -0,0 +1,108 @@
+package httpheaders
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// TraceParent is a parsed W3C Trace Context `traceparent` header.
+//
+// https://www.w3.org/TR/trace-context/#traceparent-header
+type TraceParent struct {
+	TraceID  string // 32 lowercase hex digits
+	ParentID string // 16 lowercase hex digits
+	Flags    string // 2 lowercase hex digits
+}
+
+const (
+	traceParentVersion    = "00"
+	traceParentLen        = 55
+	traceIDLen            = 32
+	parentIDLen           = 16
+	traceFlagsSampled     = "01"
+	invalidTraceID        = "00000000000000000000000000000000"
+	invalidParentID       = "0000000000000000"
+	invalidTraceVersionFF = "ff"
+)
+
+// ParseTraceParent parses a `traceparent` header value.
+//
+// Future versions are accepted as long as the version 00 fields are valid.
+func ParseTraceParent(s string) (tp TraceParent, ok bool) {
+	if len(s) < traceParentLen {
+		return tp, false
+	}
+	version := s[:2]
+	if !isLowerHex(version) || version == invalidTraceVersionFF {
+		return tp, false
+	}
+	if version == traceParentVersion && len(s) != traceParentLen {
+		return tp, false
+	}
+	if len(s) > traceParentLen && s[traceParentLen] != '-' {
+		return tp, false
+	}
+	if s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return tp, false
+	}
+	tp.TraceID = s[3:35]
+	tp.ParentID = s[36:52]
+	tp.Flags = s[53:55]
+	if !isLowerHex(tp.TraceID) || tp.TraceID == invalidTraceID ||
+		!isLowerHex(tp.ParentID) || tp.ParentID == invalidParentID ||
+		!isLowerHex(tp.Flags) {
+		return TraceParent{}, false
+	}
+	return tp, true
+}
+
+// NewTraceParent returns a sampled trace parent with a random trace ID and parent ID.
+func NewTraceParent() TraceParent {
+	return TraceParent{
+		TraceID:  randomHex(traceIDLen / 2),
+		ParentID: randomHex(parentIDLen / 2),
+		Flags:    traceFlagsSampled,
+	}
+}
+
+// NewSpan returns a trace parent in the same trace with a new parent ID.
+func (tp TraceParent) NewSpan() TraceParent {
+	tp.ParentID = randomHex(parentIDLen / 2)
+	return tp
+}
+
+func (tp TraceParent) String() string {
+	return traceParentVersion + "-" + tp.TraceID + "-" + tp.ParentID + "-" + tp.Flags
+}
+
+// TraceID returns the trace ID of the `traceparent` header, or an empty string if it is absent or invalid.
+func TraceID(h http.Header) string {
+	tp, ok := ParseTraceParent(h.Get(HeaderTraceParent))
+	if !ok {
+		return ""
+	}
+	return tp.TraceID
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func randomHex(n int) string {
+	for {
+		b := make([]byte, n)
+		_, _ = rand.Read(b)
+		s := hex.EncodeToString(b)
+		// all zeros is invalid
+		if strings.Trim(s, "0") != "" {
+			return s
+		}
+	}
+}
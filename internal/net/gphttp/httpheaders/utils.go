@@ -15,11 +15,16 @@ const (
 	HeaderXForwardedHost   = "X-Forwarded-Host"
 	HeaderXForwardedPort   = "X-Forwarded-Port"
 	HeaderXForwardedURI    = "X-Forwarded-Uri"
+	HeaderXForwardedPrefix = "X-Forwarded-Prefix"
 	HeaderXRealIP          = "X-Real-IP"
 
 	HeaderContentType   = "Content-Type"
 	HeaderContentLength = "Content-Length"
 
+	HeaderXRequestID  = "X-Request-ID"
+	HeaderTraceParent = "Traceparent"
+	HeaderTraceState  = "Tracestate"
+
 	HeaderGoDoxyCheckRedirect = "X-Godoxy-Check-Redirect"
 )
 
@@ -0,0 +1,168 @@
+package loadbalancer
+
+import (
+	"hash/fnv"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/yusing/go-proxy/internal/gperr"
+	"github.com/yusing/go-proxy/internal/serialization"
+)
+
+// hashLB picks the server by weighted rendezvous hashing of a request key,
+// adding or removing a server only remaps the keys of that server.
+//
+// Requests without the key, and stream connections, are hashed by client IP.
+type hashLB struct {
+	*LoadBalancer
+	HashOptions
+
+	source hashKeySource
+	name   string
+	// segments is the number of leading path segments for the path source.
+	segments int
+}
+
+type HashOptions struct {
+	// Key is the request key to hash, in the form of `<source>:<name>`:
+	//
+	//	header:X-Tenant-ID
+	//	cookie:session
+	//	query:tenant
+	//	path:2 (first 2 path segments, default 1)
+	Key string `json:"key"`
+}
+
+type hashKeySource string
+
+const (
+	hashKeyHeader hashKeySource = "header"
+	hashKeyCookie hashKeySource = "cookie"
+	hashKeyQuery  hashKeySource = "query"
+	hashKeyPath   hashKeySource = "path"
+)
+
+var ErrInvalidHashKey = gperr.New("invalid hash key")
+
+func (lb *LoadBalancer) newHash() impl {
+	impl := &hashLB{LoadBalancer: lb}
+	if len(lb.Options) == 0 {
+		return impl
+	}
+	if err := serialization.MapUnmarshalValidate(lb.Options, &impl.HashOptions); err != nil {
+		gperr.LogError("invalid hash options, ignoring", err, &impl.l)
+		return impl
+	}
+	if err := impl.parseKey(); err != nil {
+		gperr.LogError("invalid hash options, ignoring", err, &impl.l)
+		impl.source = ""
+	}
+	return impl
+}
+
+func (impl *hashLB) parseKey() gperr.Error {
+	if impl.Key == "" {
+		return nil
+	}
+	source, name, _ := strings.Cut(impl.Key, ":")
+	impl.source = hashKeySource(strings.ToLower(source))
+	impl.name = name
+	switch impl.source {
+	case hashKeyHeader, hashKeyCookie, hashKeyQuery:
+		if name == "" {
+			return ErrInvalidHashKey.Withf("%s name is required", source)
+		}
+	case hashKeyPath:
+		impl.segments = 1
+		if name != "" {
+			n, err := strconv.Atoi(name)
+			if err != nil || n <= 0 {
+				return ErrInvalidHashKey.Withf("invalid number of path segments %q", name)
+			}
+			impl.segments = n
+		}
+	default:
+		return ErrInvalidHashKey.Subject(impl.Key)
+	}
+	return nil
+}
+
+func (impl *hashLB) OnAddServer(Server)    {}
+func (impl *hashLB) OnRemoveServer(Server) {}
+
+func (impl *hashLB) ServeHTTP(srvs Servers, rw http.ResponseWriter, r *http.Request) {
+	key := impl.requestKey(r)
+	if key == "" {
+		key, _, _ = net.SplitHostPort(r.RemoteAddr)
+	}
+	srv, _ := impl.NextStream(srvs, key)
+	srv.ServeHTTP(rw, r)
+}
+
+func (impl *hashLB) NextStream(srvs Servers, key string) (Server, func()) {
+	return rendezvous(srvs, key), nil
+}
+
+func (impl *hashLB) requestKey(r *http.Request) string {
+	switch impl.source {
+	case hashKeyHeader:
+		return r.Header.Get(impl.name)
+	case hashKeyCookie:
+		if cookie, err := r.Cookie(impl.name); err == nil {
+			return cookie.Value
+		}
+	case hashKeyQuery:
+		return r.URL.Query().Get(impl.name)
+	case hashKeyPath:
+		return pathPrefix(r.URL.Path, impl.segments)
+	}
+	return ""
+}
+
+// pathPrefix returns the first n segments of the path, e.g. `/a/b` for `/a/b/c` and n = 2.
+func pathPrefix(path string, n int) string {
+	path = strings.TrimPrefix(path, "/")
+	end := 0
+	for range n {
+		i := strings.IndexByte(path[end:], '/')
+		if i < 0 {
+			return "/" + path
+		}
+		end += i + 1
+	}
+	return "/" + path[:end-1]
+}
+
+// rendezvous returns the server with the highest weighted score for the key.
+//
+// https://en.wikipedia.org/wiki/Rendezvous_hashing#Weighted_rendezvous_hash
+func rendezvous(srvs Servers, key string) Server {
+	var best Server
+	bestScore := math.Inf(-1)
+	for _, srv := range srvs {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		h.Write([]byte(srv.Key()))
+		// uniform in (0, 1)
+		u := (float64(mix64(h.Sum64())>>11) + 0.5) / (1 << 53)
+		score := -float64(effectiveWeight(srv)) / math.Log(u)
+		if best == nil || score > bestScore {
+			best, bestScore = srv, score
+		}
+	}
+	return best
+}
+
+// mix64 is the finalizer of splitmix64, it improves the distribution of FNV hashes.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -4,6 +4,7 @@ import (
 	"hash/fnv"
 	"net"
 	"net/http"
+	"slices"
 	"sync"
 
 	"github.com/yusing/go-proxy/internal/gperr"
@@ -60,30 +61,50 @@ func (impl *ipHash) OnRemoveServer(srv Server) {
 	}
 }
 
-func (impl *ipHash) ServeHTTP(_ Servers, rw http.ResponseWriter, r *http.Request) {
+func (impl *ipHash) ServeHTTP(srvs Servers, rw http.ResponseWriter, r *http.Request) {
 	if impl.realIP != nil {
-		impl.realIP.ModifyRequest(impl.serveHTTP, rw, r)
+		impl.realIP.ModifyRequest(func(rw http.ResponseWriter, r *http.Request) {
+			impl.serveHTTP(srvs, rw, r)
+		}, rw, r)
 	} else {
-		impl.serveHTTP(rw, r)
+		impl.serveHTTP(srvs, rw, r)
 	}
 }
 
-func (impl *ipHash) serveHTTP(rw http.ResponseWriter, r *http.Request) {
+func (impl *ipHash) serveHTTP(srvs Servers, rw http.ResponseWriter, r *http.Request) {
 	ip, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
 		http.Error(rw, "Internal error", http.StatusInternalServerError)
 		impl.l.Err(err).Msg("invalid remote address " + r.RemoteAddr)
 		return
 	}
-	idx := hashIP(ip) % uint32(len(impl.pool))
-
-	srv := impl.pool[idx]
-	if srv == nil || srv.Status().Bad() {
+	srv, _ := impl.NextStream(srvs, ip)
+	if srv == nil {
 		http.Error(rw, "Service unavailable", http.StatusServiceUnavailable)
+		return
 	}
 	srv.ServeHTTP(rw, r)
 }
 
+// NextStream picks the server by the hash of srcIP.
+//
+// If that server is not available (e.g. unhealthy, or a backup server while primary servers are up),
+// the server is picked from the available servers instead.
+func (impl *ipHash) NextStream(srvs Servers, srcIP string) (Server, func()) {
+	impl.mu.Lock()
+	defer impl.mu.Unlock()
+
+	if len(impl.pool) == 0 || len(srvs) == 0 {
+		return nil, nil
+	}
+	hash := hashIP(srcIP)
+	srv := impl.pool[hash%uint32(len(impl.pool))]
+	if srv != nil && slices.Contains(srvs, srv) {
+		return srv, nil
+	}
+	return srvs[hash%uint32(len(srvs))], nil
+}
+
 func hashIP(ip string) uint32 {
 	h := fnv.New32a()
 	h.Write([]byte(ip))
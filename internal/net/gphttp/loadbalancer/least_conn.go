@@ -7,6 +7,7 @@ import (
 	F "github.com/yusing/go-proxy/internal/utils/functional"
 )
 
+// leastConn picks the server with the least active connections relative to its weight.
 type leastConn struct {
 	*LoadBalancer
 	nConn F.Map[Server, *atomic.Int64]
@@ -28,26 +29,40 @@ func (impl *leastConn) OnRemoveServer(srv Server) {
 }
 
 func (impl *leastConn) ServeHTTP(srvs Servers, rw http.ResponseWriter, r *http.Request) {
-	srv := srvs[0]
-	minConn, ok := impl.nConn.Load(srv)
-	if !ok {
-		impl.l.Error().Msgf("[BUG] server %s not found", srv.Name())
+	srv, release := impl.NextStream(srvs, "")
+	if srv == nil {
 		http.Error(rw, "Internal error", http.StatusInternalServerError)
+		return
 	}
+	defer release()
+	srv.ServeHTTP(rw, r)
+}
+
+func (impl *leastConn) NextStream(srvs Servers, _ string) (Server, func()) {
+	var srv Server
+	var minConn *atomic.Int64
+	var minConnN int64
+	var minWeight Weight
 
-	for i := 1; i < len(srvs); i++ {
-		nConn, ok := impl.nConn.Load(srvs[i])
+	for _, s := range srvs {
+		nConn, ok := impl.nConn.Load(s)
 		if !ok {
-			impl.l.Error().Msgf("[BUG] server %s not found", srv.Name())
-			http.Error(rw, "Internal error", http.StatusInternalServerError)
+			impl.l.Error().Msgf("[BUG] server %s not found", s.Name())
+			continue
 		}
-		if nConn.Load() < minConn.Load() {
-			minConn = nConn
-			srv = srvs[i]
+		n, w := nConn.Load(), effectiveWeight(s)
+		// n/w < minConnN/minWeight, or same ratio but higher weight
+		if srv == nil ||
+			n*int64(minWeight) < minConnN*int64(w) ||
+			(n*int64(minWeight) == minConnN*int64(w) && w > minWeight) {
+			srv, minConn, minConnN, minWeight = s, nConn, n, w
 		}
 	}
 
+	if srv == nil {
+		return nil, nil
+	}
+
 	minConn.Add(1)
-	srv.ServeHTTP(rw, r)
-	minConn.Add(-1)
+	return srv, func() { minConn.Add(-1) }
 }
@@ -0,0 +1,82 @@
+package loadbalancer
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// leastLatency picks the server with the lowest latency cost.
+//
+// Stream connections have no response times, so they are effectively balanced by least connections.
+type leastLatency struct{}
+
+// p2c (power of two choices) picks two random servers
+// and chooses the one with the lower latency cost.
+//
+// It is cheaper than leastLatency and avoids sending bursts of requests
+// to the same server when the latency information is stale.
+type p2c struct{}
+
+// minLatencyCost is the latency assumed for servers without samples,
+// so new servers are probed without receiving every request.
+const minLatencyCost = time.Millisecond
+
+func (*LoadBalancer) newLeastLatency() impl {
+	return leastLatency{}
+}
+
+func (*LoadBalancer) newP2C() impl {
+	return p2c{}
+}
+
+func (leastLatency) OnAddServer(Server)    {}
+func (leastLatency) OnRemoveServer(Server) {}
+
+func (impl leastLatency) ServeHTTP(srvs Servers, rw http.ResponseWriter, r *http.Request) {
+	srv, _ := impl.NextStream(srvs, "")
+	srv.ServeHTTP(rw, r)
+}
+
+func (leastLatency) NextStream(srvs Servers, _ string) (Server, func()) {
+	var best Server
+	var bestCost float64
+	for _, srv := range srvs {
+		if cost := latencyCost(srv); best == nil || cost < bestCost {
+			best, bestCost = srv, cost
+		}
+	}
+	return best, nil
+}
+
+func (p2c) OnAddServer(Server)    {}
+func (p2c) OnRemoveServer(Server) {}
+
+func (impl p2c) ServeHTTP(srvs Servers, rw http.ResponseWriter, r *http.Request) {
+	srv, _ := impl.NextStream(srvs, "")
+	srv.ServeHTTP(rw, r)
+}
+
+func (p2c) NextStream(srvs Servers, _ string) (Server, func()) {
+	if len(srvs) == 1 {
+		return srvs[0], nil
+	}
+	i := rand.IntN(len(srvs))
+	j := rand.IntN(len(srvs) - 1)
+	if j >= i {
+		j++
+	}
+	a, b := srvs[i], srvs[j]
+	if latencyCost(b) < latencyCost(a) {
+		return b, nil
+	}
+	return a, nil
+}
+
+// latencyCost returns the expected latency of sending a request to the server,
+// the EWMA latency multiplied by the number of in-flight requests plus one, divided by the weight.
+func latencyCost(srv Server) float64 {
+	stats := srv.Stats()
+	latency := max(stats.EWMALatency(), minLatencyCost)
+	return float64(latency) * float64(stats.Active()+1) / float64(effectiveWeight(srv))
+}
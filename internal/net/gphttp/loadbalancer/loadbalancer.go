@@ -3,7 +3,9 @@ package loadbalancer
 import (
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -11,16 +13,21 @@ import (
 	"github.com/yusing/go-proxy/internal/gperr"
 	"github.com/yusing/go-proxy/internal/net/gphttp/httpheaders"
 	"github.com/yusing/go-proxy/internal/net/gphttp/loadbalancer/types"
+	"github.com/yusing/go-proxy/internal/notif"
 	"github.com/yusing/go-proxy/internal/task"
+	F "github.com/yusing/go-proxy/internal/utils/functional"
 	"github.com/yusing/go-proxy/internal/utils/pool"
+	"github.com/yusing/go-proxy/internal/utils/strutils"
 	"github.com/yusing/go-proxy/internal/watcher/health"
 )
 
-// TODO: stats of each server.
-// TODO: support weighted mode.
 type (
 	impl interface {
 		ServeHTTP(srvs Servers, rw http.ResponseWriter, r *http.Request)
+		// NextStream selects a server for a new stream connection from srcIP.
+		//
+		// release, if not nil, is called when the connection is closed.
+		NextStream(srvs Servers, srcIP string) (srv Server, release func())
 		OnAddServer(srv Server)
 		OnRemoveServer(srv Server)
 	}
@@ -37,6 +44,16 @@ type (
 		sumWeight Weight
 		startTime time.Time
 
+		// variantWeights are the variant weights set at runtime by SetVariantWeights,
+		// kept apart from the configured weights so they survive pool changes.
+		variantWeights map[string]Weight
+
+		// failover is true when all primary servers are down and backup servers are serving.
+		failover atomic.Bool
+		outliers *outlierDetector
+		// healthy is the last known health of each server, for slow start.
+		healthy F.Map[Server, bool]
+
 		l zerolog.Logger
 	}
 )
@@ -45,9 +62,10 @@ const maxWeight Weight = 100
 
 func New(cfg *Config) *LoadBalancer {
 	lb := &LoadBalancer{
-		Config: new(Config),
-		pool:   pool.New[Server]("loadbalancer." + cfg.Link),
-		l:      log.With().Str("name", cfg.Link).Logger(),
+		Config:  new(Config),
+		pool:    pool.New[Server]("loadbalancer." + cfg.Link),
+		healthy: F.NewMapOf[Server, bool](),
+		l:       log.With().Str("name", cfg.Link).Logger(),
 	}
 	lb.UpdateConfigIfNeeded(cfg)
 	return lb
@@ -86,6 +104,16 @@ func (lb *LoadBalancer) updateImpl() {
 		lb.impl = lb.newLeastConn()
 	case types.ModeIPHash:
 		lb.impl = lb.newIPHash()
+	case types.ModeSticky:
+		lb.impl = lb.newSticky()
+	case types.ModeLeastLatency:
+		lb.impl = lb.newLeastLatency()
+	case types.ModeP2C:
+		lb.impl = lb.newP2C()
+	case types.ModeHash:
+		lb.impl = lb.newHash()
+	case types.ModeSplit:
+		lb.impl = lb.newSplit()
 	default: // should happen in test only
 		lb.impl = lb.newRoundRobin()
 	}
@@ -101,6 +129,24 @@ func (lb *LoadBalancer) UpdateConfigIfNeeded(cfg *Config) {
 
 		lb.Link = cfg.Link
 
+		// options must be set before updateImpl, they are read by the mode implementation
+		if len(lb.Options) == 0 && len(cfg.Options) > 0 {
+			lb.Options = cfg.Options
+		}
+
+		if lb.outliers == nil && cfg.OutlierDetection != nil {
+			lb.OutlierDetection = cfg.OutlierDetection
+			lb.outliers = newOutlierDetector(cfg.OutlierDetection)
+		}
+
+		if lb.Locality == nil && cfg.Locality != nil {
+			lb.Locality = cfg.Locality
+		}
+
+		if lb.SlowStart == 0 && cfg.SlowStart > 0 {
+			lb.SlowStart = cfg.SlowStart
+		}
+
 		if lb.Mode == types.ModeUnset && cfg.Mode != types.ModeUnset {
 			lb.Mode = cfg.Mode
 			if !lb.Mode.ValidateUpdate() {
@@ -108,10 +154,6 @@ func (lb *LoadBalancer) UpdateConfigIfNeeded(cfg *Config) {
 			}
 			lb.updateImpl()
 		}
-
-		if len(lb.Options) == 0 && len(cfg.Options) > 0 {
-			lb.Options = cfg.Options
-		}
 	}
 
 	if lb.impl == nil {
@@ -124,14 +166,16 @@ func (lb *LoadBalancer) AddServer(srv Server) {
 	defer lb.poolMu.Unlock()
 
 	if old, ok := lb.pool.Get(srv.Key()); ok { // FIXME: this should be a warning
-		lb.sumWeight -= old.Weight()
 		lb.impl.OnRemoveServer(old)
 		lb.pool.Del(old)
 	}
 	lb.pool.Add(srv)
-	lb.sumWeight += srv.Weight()
+	if lb.SlowStart > 0 {
+		srv.StartSlowStart(lb.SlowStart)
+	}
 
 	lb.rebalance()
+	lb.reapplyVariantWeights()
 	lb.impl.OnAddServer(srv)
 }
 
@@ -145,9 +189,11 @@ func (lb *LoadBalancer) RemoveServer(srv Server) {
 
 	lb.pool.Del(srv)
 
-	lb.sumWeight -= srv.Weight()
 	lb.rebalance()
+	lb.reapplyVariantWeights()
 	lb.impl.OnRemoveServer(srv)
+	lb.removeOutlier(srv)
+	lb.healthy.Delete(srv)
 
 	lb.l.Debug().
 		Str("action", "remove").
@@ -161,6 +207,11 @@ func (lb *LoadBalancer) RemoveServer(srv Server) {
 }
 
 func (lb *LoadBalancer) rebalance() {
+	// weights may have been changed at runtime by Server.SetWeight
+	lb.sumWeight = 0
+	for _, srv := range lb.pool.Iter {
+		lb.sumWeight += srv.Weight()
+	}
 	if lb.sumWeight == maxWeight {
 		return
 	}
@@ -174,11 +225,11 @@ func (lb *LoadBalancer) rebalance() {
 		remainder := maxWeight % Weight(poolSize)
 		for _, srv := range lb.pool.Iter {
 			w := weightEach
-			lb.sumWeight += weightEach
 			if remainder > 0 {
 				w++
 				remainder--
 			}
+			lb.sumWeight += w
 			srv.SetWeight(w)
 		}
 		return
@@ -235,8 +286,12 @@ func (lb *LoadBalancer) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 // MarshalJSON implements health.HealthMonitor.
 func (lb *LoadBalancer) MarshalJSON() ([]byte, error) {
 	extra := make(map[string]any)
+	ejected := make([]string, 0)
 	for _, srv := range lb.pool.Iter {
 		extra[srv.Key()] = srv
+		if lb.IsEjected(srv) {
+			ejected = append(ejected, srv.Key())
+		}
 	}
 
 	status, numHealthy := lb.status()
@@ -249,12 +304,37 @@ func (lb *LoadBalancer) MarshalJSON() ([]byte, error) {
 		Uptime:  lb.Uptime(),
 		Latency: lb.Latency(),
 		Extra: map[string]any{
-			"config": lb.Config,
-			"pool":   extra,
+			"config":   lb.Config,
+			"pool":     extra,
+			"stats":    lb.Stats(),
+			"zones":    lb.ZoneStats(),
+			"variants": lb.VariantStats(),
+			"failover": lb.failover.Load(),
+			"ejected":  ejected,
 		},
 	}).MarshalJSON()
 }
 
+// Stats returns the traffic statistics of each server, keyed by server key.
+func (lb *LoadBalancer) Stats() map[string]types.ServerStatsSnapshot {
+	stats := make(map[string]types.ServerStatsSnapshot, lb.pool.Size())
+	for _, srv := range lb.pool.Iter {
+		stats[srv.Key()] = srv.Stats().Snapshot()
+	}
+	return stats
+}
+
+// ZoneStats returns the traffic statistics aggregated by zone.
+func (lb *LoadBalancer) ZoneStats() map[string]types.ServerStatsSnapshot {
+	stats := make(map[string]types.ServerStatsSnapshot)
+	for _, srv := range lb.pool.Iter {
+		zone := stats[srv.Zone()]
+		zone.Add(srv.Stats().Snapshot())
+		stats[srv.Zone()] = zone
+	}
+	return stats
+}
+
 // Name implements health.HealthMonitor.
 func (lb *LoadBalancer) Name() string {
 	return lb.Link
@@ -280,7 +360,7 @@ func (lb *LoadBalancer) status() (status health.Status, numHealthy int) {
 	// should be healthy if at least one server is healthy
 	numHealthy = 0
 	for _, srv := range lb.pool.Iter {
-		if srv.Status().Good() {
+		if srv.Status().Good() && !lb.IsEjected(srv) {
 			numHealthy++
 		}
 	}
@@ -309,12 +389,116 @@ func (lb *LoadBalancer) String() string {
 	return lb.Name()
 }
 
+// IsEjected returns whether the server is ejected by outlier detection.
+func (lb *LoadBalancer) IsEjected(srv Server) bool {
+	return lb.outliers != nil && lb.outliers.isEjected(srv)
+}
+
+// Servers returns all servers in the pool, including unhealthy ones.
+func (lb *LoadBalancer) Servers() []Server {
+	srvs := make([]Server, 0, lb.pool.Size())
+	for _, srv := range lb.pool.Iter {
+		srvs = append(srvs, srv)
+	}
+	return srvs
+}
+
+// availServers returns the healthy primary servers that are not ejected,
+// or the healthy backup servers that are not ejected if there are none.
 func (lb *LoadBalancer) availServers() []Server {
-	avail := make([]Server, 0, lb.pool.Size())
+	healthy := make([]Server, 0, lb.pool.Size())
 	for _, srv := range lb.pool.Iter {
-		if srv.Status().Good() {
+		good := srv.Status().Good()
+		if lb.SlowStart > 0 {
+			if wasGood, ok := lb.healthy.LoadAndStore(srv, good); ok && !wasGood && good {
+				srv.StartSlowStart(lb.SlowStart)
+			}
+		}
+		if good {
+			healthy = append(healthy, srv)
+		}
+	}
+	ejected := lb.ejectedServers(healthy)
+
+	avail := make([]Server, 0, len(healthy))
+	var backups []Server
+	for _, srv := range healthy {
+		if ejected[srv] {
+			continue
+		}
+		if srv.Priority() == types.PriorityBackup {
+			backups = append(backups, srv)
+		} else {
 			avail = append(avail, srv)
 		}
 	}
-	return avail
+	switch {
+	case len(avail) > 0:
+		lb.setFailover(false, avail)
+	case len(backups) > 0:
+		lb.setFailover(true, backups)
+		return lb.preferLocal(backups)
+	}
+	return lb.preferLocal(avail)
+}
+
+// preferLocal returns the servers in the local zone,
+// or all servers if there are none or they are overloaded.
+func (lb *LoadBalancer) preferLocal(srvs []Server) []Server {
+	loc := lb.Locality
+	if loc == nil {
+		return srvs
+	}
+	localZone := loc.LocalZone
+	if localZone == "" {
+		localZone = types.ZoneLocal
+	}
+
+	local := make([]Server, 0, len(srvs))
+	var active int64
+	for _, srv := range srvs {
+		if srv.Zone() == localZone {
+			local = append(local, srv)
+			active += srv.Stats().Active()
+		}
+	}
+	if len(local) == 0 || len(local) == len(srvs) {
+		return srvs
+	}
+	if loc.MaxActive > 0 && active >= int64(loc.MaxActive*len(local)) {
+		return srvs // spill over
+	}
+	return local
+}
+
+// setFailover updates the failover state and sends a notification if it has changed.
+func (lb *LoadBalancer) setFailover(failover bool, srvs []Server) {
+	if !lb.failover.CompareAndSwap(!failover, failover) {
+		return
+	}
+
+	names := make([]string, len(srvs))
+	for i, srv := range srvs {
+		names[i] = srv.Name()
+	}
+	extras := notif.FieldsBody{
+		{Name: "Load Balancer", Value: lb.Link},
+		{Name: "Serving", Value: strings.Join(names, ", ")},
+		{Name: "Time", Value: strutils.FormatTime(time.Now())},
+	}
+	if failover {
+		lb.l.Warn().Strs("servers", names).Msg("all primary servers are down, failing over to backup servers")
+		notif.Notify(&notif.LogMessage{
+			Title: "⚠️ Failed over to backup servers ⚠️",
+			Body:  extras,
+			Color: notif.ColorError,
+		})
+	} else {
+		lb.l.Info().Strs("servers", names).Msg("primary servers are back up, failing back")
+		notif.Notify(&notif.LogMessage{
+			Title: "✅ Failed back to primary servers ✅",
+			Body:  extras,
+			Color: notif.ColorSuccess,
+		})
+	}
 }
@@ -1,9 +1,17 @@
 package loadbalancer
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/yusing/go-proxy/internal/net/gphttp/loadbalancer/types"
+	net "github.com/yusing/go-proxy/internal/net/types"
 	. "github.com/yusing/go-proxy/internal/utils/testing"
 )
 
@@ -42,3 +50,429 @@ func TestRebalance(t *testing.T) {
 		ExpectEqual(t, lb.sumWeight, maxWeight)
 	})
 }
+
+func TestWeightedRoundRobin(t *testing.T) {
+	t.Parallel()
+	srvs := Servers{
+		types.TestNewServer(5),
+		types.TestNewServer(1),
+		types.TestNewServer(1),
+	}
+	rr := new(LoadBalancer).newRoundRobin().(*roundRobin)
+	for _, srv := range srvs {
+		rr.OnAddServer(srv)
+	}
+
+	pick := func(n int) map[Server]int {
+		counts := make(map[Server]int)
+		for range n {
+			counts[rr.next(srvs)]++
+		}
+		return counts
+	}
+
+	counts := pick(70)
+	ExpectEqual(t, counts[srvs[0]], 50)
+	ExpectEqual(t, counts[srvs[1]], 10)
+	ExpectEqual(t, counts[srvs[2]], 10)
+
+	srvs[1].SetWeight(5)
+	counts = pick(110)
+	ExpectEqual(t, counts[srvs[0]], 50)
+	ExpectEqual(t, counts[srvs[1]], 50)
+	ExpectEqual(t, counts[srvs[2]], 10)
+}
+
+func TestServerStats(t *testing.T) {
+	t.Parallel()
+	srv := types.NewServer("test", net.MustParseURL("http://localhost"), 1, types.PriorityPrimary, types.ZoneLocal, "", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte("hello"))
+	}), nil)
+
+	for _, path := range []string{"/", "/", "/error"} {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader("body"))
+		srv.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	stats := srv.Stats().Snapshot()
+	ExpectEqual(t, stats.Requests, 3)
+	ExpectEqual(t, stats.Active, 0)
+	ExpectEqual(t, stats.Errors, 1)
+	ExpectEqual(t, stats.BytesIn, 12)
+	ExpectEqual(t, stats.BytesOut, 10)
+	ExpectEqual(t, stats.Latency[len(stats.Latency)-1].Count, 3)
+}
+
+func TestSticky(t *testing.T) {
+	t.Parallel()
+	newServer := func(name string) Server {
+		return types.NewServer(name, net.MustParseURL("http://"+name), 1, types.PriorityPrimary, types.ZoneLocal, "", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(name))
+		}), nil)
+	}
+	srvs := Servers{newServer("a"), newServer("b")}
+	lb := New(&types.Config{Link: "test", Mode: types.ModeSticky})
+	for _, srv := range srvs {
+		lb.impl.OnAddServer(srv)
+	}
+
+	serve := func(srvs Servers, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		lb.impl.ServeHTTP(srvs, rec, req)
+		return rec
+	}
+
+	rec := serve(srvs, nil)
+	pinned := rec.Body.String()
+	cookies := rec.Result().Cookies()
+	ExpectEqual(t, len(cookies), 1)
+	cookie := cookies[0]
+	ExpectEqual(t, cookie.Name, stickyCookieDefault)
+
+	for range 5 {
+		rec = serve(srvs, cookie)
+		ExpectEqual(t, rec.Body.String(), pinned)
+		ExpectEqual(t, len(rec.Result().Cookies()), 0)
+	}
+
+	t.Run("tampered", func(t *testing.T) {
+		tampered := *cookie
+		tampered.Value = "Yg." + strings.Repeat("0", 64) // "b"
+		rec := serve(srvs, &tampered)
+		ExpectEqual(t, len(rec.Result().Cookies()), 1)
+	})
+
+	t.Run("server_gone", func(t *testing.T) {
+		var remaining Servers
+		for _, srv := range srvs {
+			if srv.Name() != pinned {
+				remaining = append(remaining, srv)
+			}
+		}
+		rec := serve(remaining, cookie)
+		NotEqual(t, rec.Body.String(), pinned)
+		ExpectEqual(t, len(rec.Result().Cookies()), 1)
+	})
+}
+
+func TestLeastLatency(t *testing.T) {
+	t.Parallel()
+	newServer := func(name string, delay time.Duration) Server {
+		return types.NewServer(name, net.MustParseURL("http://"+name), 1, types.PriorityPrimary, types.ZoneLocal, "", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(delay)
+		}), nil)
+	}
+	fast := newServer("fast", 0)
+	slow := newServer("slow", 20*time.Millisecond)
+	srvs := Servers{slow, fast}
+	for _, srv := range srvs {
+		srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	for _, impl := range []impl{new(LoadBalancer).newLeastLatency(), new(LoadBalancer).newP2C()} {
+		for range 10 {
+			srv, _ := impl.NextStream(srvs, "")
+			ExpectEqual(t, srv, fast)
+		}
+	}
+}
+
+func TestHash(t *testing.T) {
+	t.Parallel()
+	srvs := make(Servers, 5)
+	for i := range srvs {
+		host := fmt.Sprintf("10.0.0.%d:80", i+1)
+		srvs[i] = types.NewServer(host, net.MustParseURL("http://"+host), 1, types.PriorityPrimary, types.ZoneLocal, "", nil, nil)
+	}
+	lb := New(&types.Config{
+		Link:    "test",
+		Mode:    types.ModeHash,
+		Options: map[string]any{"key": "header:X-Tenant-ID"},
+	})
+	impl := lb.impl.(*hashLB)
+
+	t.Run("key", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Tenant-ID", "tenant")
+		ExpectEqual(t, impl.requestKey(req), "tenant")
+	})
+
+	t.Run("path_prefix", func(t *testing.T) {
+		ExpectEqual(t, pathPrefix("/a/b/c", 2), "/a/b")
+		ExpectEqual(t, pathPrefix("/a", 2), "/a")
+		ExpectEqual(t, pathPrefix("/", 1), "/")
+	})
+
+	t.Run("consistent", func(t *testing.T) {
+		const numKeys = 1000
+		before := make([]Server, numKeys)
+		for i := range numKeys {
+			before[i], _ = impl.NextStream(srvs, strconv.Itoa(i))
+		}
+		removed := srvs[2]
+		remaining := slices.Delete(slices.Clone(srvs), 2, 3)
+		for i := range numKeys {
+			after, _ := impl.NextStream(remaining, strconv.Itoa(i))
+			if before[i] != removed {
+				ExpectEqual(t, after, before[i])
+			}
+		}
+	})
+}
+
+func TestOutlierDetection(t *testing.T) {
+	t.Parallel()
+	newServer := func(name string) Server {
+		return types.NewServer(name, net.MustParseURL("http://"+name), 1, types.PriorityPrimary, types.ZoneLocal, "", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}), nil)
+	}
+	fail := func(srv Server, n int) {
+		for range n {
+			srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		}
+	}
+	a, b := newServer("a"), newServer("b")
+	srvs := Servers{a, b}
+	lb := New(&types.Config{
+		Link: "test",
+		OutlierDetection: &types.OutlierDetectionConfig{
+			ConsecutiveErrors:  2,
+			MaxEjectionPercent: 50,
+		},
+	})
+
+	fail(a, 1)
+	ExpectEqual(t, len(lb.ejectedServers(srvs)), 0)
+
+	fail(a, 1)
+	ejected := lb.ejectedServers(srvs)
+	ExpectTrue(t, ejected[a])
+	ExpectTrue(t, lb.IsEjected(a))
+	ExpectEqual(t, a.Stats().ConsecutiveErrors(), 0)
+
+	// capped at 50%
+	fail(b, 2)
+	ejected = lb.ejectedServers(srvs)
+	ExpectTrue(t, ejected[a])
+	ExpectFalse(t, ejected[b])
+}
+
+func TestOutlierDetectionPanicThreshold(t *testing.T) {
+	t.Parallel()
+	newServer := func(name string) Server {
+		return types.NewServer(name, net.MustParseURL("http://"+name), 1, types.PriorityPrimary, types.ZoneLocal, "", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}), nil)
+	}
+	fail := func(srv Server, n int) {
+		for range n {
+			srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		}
+	}
+	lb := New(&types.Config{
+		Link: "test",
+		OutlierDetection: &types.OutlierDetectionConfig{
+			ConsecutiveErrors:  1,
+			MaxEjectionPercent: 100,
+		},
+	})
+
+	// the only server is never ejected
+	a := newServer("a")
+	fail(a, 1)
+	ExpectEqual(t, len(lb.ejectedServers(Servers{a})), 0)
+	ExpectFalse(t, lb.IsEjected(a))
+
+	// one of two servers is ejected, the other one is kept
+	b := newServer("b")
+	fail(b, 1)
+	ejected := lb.ejectedServers(Servers{a, b})
+	ExpectTrue(t, ejected[a])
+	ExpectFalse(t, ejected[b])
+
+	// the server left turned unhealthy, the ejected server is served
+	ExpectEqual(t, len(lb.ejectedServers(Servers{a})), 0)
+	ExpectTrue(t, lb.IsEjected(a))
+}
+
+func TestUnixSocketServerKey(t *testing.T) {
+	t.Parallel()
+	a := types.NewServer("a", net.MustParseURL("unix:///run/a.sock"), 1, "", "", "", nil, nil)
+	b := types.NewServer("b", net.MustParseURL("unix:///run/b.sock"), 1, "", "", "", nil, nil)
+	ExpectEqual(t, a.Key(), "/run/a.sock")
+	ExpectEqual(t, b.Key(), "/run/b.sock")
+
+	lb := New(&types.Config{Link: "test"})
+	lb.AddServer(a)
+	lb.AddServer(b)
+	ExpectEqual(t, len(lb.Servers()), 2)
+}
+
+func TestSlowStart(t *testing.T) {
+	t.Parallel()
+	srv := types.TestNewServer(10)
+	ExpectEqual(t, srv.EffectiveWeight(), 10)
+
+	srv.StartSlowStart(time.Hour)
+	ExpectEqual(t, srv.EffectiveWeight(), 0)
+	ExpectEqual(t, effectiveWeight(srv), 1)
+
+	srv.StartSlowStart(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	ExpectEqual(t, srv.EffectiveWeight(), 10)
+}
+
+func TestPreferLocal(t *testing.T) {
+	t.Parallel()
+	block := make(chan struct{})
+	newServer := func(name, zone string) Server {
+		return types.NewServer(name, net.MustParseURL("http://"+name), 1, types.PriorityPrimary, zone, "", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-block
+		}), nil)
+	}
+	local := newServer("local", types.ZoneLocal)
+	remote := newServer("remote", "agent")
+	srvs := Servers{local, remote}
+	lb := New(&types.Config{
+		Link:     "test",
+		Locality: &types.LocalityConfig{MaxActive: 1},
+	})
+
+	ExpectEqual(t, lb.preferLocal(srvs), Servers{local})
+	ExpectEqual(t, lb.preferLocal(Servers{remote}), Servers{remote})
+
+	done := make(chan struct{})
+	go func() {
+		local.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		close(done)
+	}()
+	for local.Stats().Active() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ExpectEqual(t, lb.preferLocal(srvs), srvs) // spill over
+	close(block)
+	<-done
+}
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+	newServer := func(name, variant string, weight Weight) Server {
+		return types.NewServer(name, net.MustParseURL("http://"+name), weight, types.PriorityPrimary, types.ZoneLocal, variant, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(variant))
+		}), nil)
+	}
+	stable, canary := newServer("stable", "", 90), newServer("canary", "canary", 10)
+	lb := New(&types.Config{
+		Link: "test",
+		Mode: types.ModeSplit,
+		Options: map[string]any{
+			"rules": []any{
+				map[string]any{"header": "X-Canary", "value": "1", "variant": "canary"},
+			},
+		},
+	})
+	lb.AddServer(stable)
+	lb.AddServer(canary)
+	srvs := lb.Servers()
+
+	serve := func(header http.Header, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		lb.impl.ServeHTTP(srvs, rec, req)
+		return rec
+	}
+
+	t.Run("percentage", func(t *testing.T) {
+		ExpectNoError(t, lb.SetVariantWeights(map[string]Weight{types.VariantDefault: 90, "canary": 10}))
+		counts := make(map[string]int)
+		for range 100 {
+			counts[serve(nil, nil).Body.String()]++
+		}
+		ExpectEqual(t, counts[types.VariantDefault], 90)
+		ExpectEqual(t, counts["canary"], 10)
+	})
+
+	t.Run("rule", func(t *testing.T) {
+		for range 5 {
+			rec := serve(http.Header{"X-Canary": {"1"}}, nil)
+			ExpectEqual(t, rec.Body.String(), "canary")
+			ExpectEqual(t, len(rec.Result().Cookies()), 0)
+		}
+	})
+
+	t.Run("sticky", func(t *testing.T) {
+		for _, variant := range []string{types.VariantDefault, "canary"} {
+			cookie := &http.Cookie{Name: splitCookieDefault, Value: variant}
+			for range 5 {
+				ExpectEqual(t, serve(nil, cookie).Body.String(), variant)
+			}
+		}
+		cookies := serve(nil, nil).Result().Cookies()
+		ExpectEqual(t, len(cookies), 1)
+		ExpectEqual(t, cookies[0].Name, splitCookieDefault)
+	})
+
+	t.Run("set_weights", func(t *testing.T) {
+		ExpectNoError(t, lb.SetVariantWeights(map[string]Weight{types.VariantDefault: 100, "canary": 0}))
+		ExpectEqual(t, lb.Variants(), map[string]Weight{types.VariantDefault: 100, "canary": 0})
+
+		// pinned clients are reassigned, rules still apply
+		cookie := &http.Cookie{Name: splitCookieDefault, Value: "canary"}
+		ExpectEqual(t, serve(nil, cookie).Body.String(), types.VariantDefault)
+		ExpectEqual(t, serve(http.Header{"X-Canary": {"1"}}, nil).Body.String(), "canary")
+
+		ExpectHasError(t, lb.SetVariantWeights(map[string]Weight{types.VariantDefault: 50}))
+		ExpectHasError(t, lb.SetVariantWeights(map[string]Weight{types.VariantDefault: 50, "canary": 10}))
+		ExpectHasError(t, lb.SetVariantWeights(map[string]Weight{types.VariantDefault: 50, "canary": 40, "unknown": 10}))
+		ExpectEqual(t, lb.Variants(), map[string]Weight{types.VariantDefault: 100, "canary": 0})
+	})
+
+	t.Run("weights_on_pool_change", func(t *testing.T) {
+		// kept when a server of an existing variant is added or removed
+		stable2 := newServer("stable2", "", 50)
+		lb.AddServer(stable2)
+		ExpectEqual(t, lb.Variants(), map[string]Weight{types.VariantDefault: 100, "canary": 0})
+		lb.RemoveServer(stable2)
+		ExpectEqual(t, lb.Variants(), map[string]Weight{types.VariantDefault: 100, "canary": 0})
+
+		// discarded when the variants change, the current weights are rebalanced
+		lb.AddServer(newServer("beta", "beta", 100))
+		ExpectTrue(t, lb.variantWeights == nil)
+		ExpectEqual(t, lb.Variants(), map[string]Weight{types.VariantDefault: 50, "canary": 0, "beta": 50})
+	})
+}
+
+func TestStatusCodes(t *testing.T) {
+	t.Parallel()
+	newServer := func(name string, code int) Server {
+		return types.NewServer(name, net.MustParseURL("http://"+name), 1, types.PriorityPrimary, types.ZoneLocal, "canary", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(code)
+		}), nil)
+	}
+	lb := New(&types.Config{Link: "test", Mode: types.ModeSplit})
+	ok, failing := newServer("ok", http.StatusOK), newServer("failing", http.StatusBadGateway)
+	lb.AddServer(ok)
+	lb.AddServer(failing)
+	for _, srv := range []Server{ok, ok, failing} {
+		srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	stats := lb.VariantStats()["canary"]
+	ExpectEqual(t, stats.Requests, 3)
+	ExpectEqual(t, stats.Errors, 1)
+	ExpectEqual(t, stats.StatusCodes, map[string]int64{"2xx": 2, "5xx": 1})
+}
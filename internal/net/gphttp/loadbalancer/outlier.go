@@ -0,0 +1,175 @@
+package loadbalancer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/yusing/go-proxy/internal/net/gphttp/loadbalancer/types"
+	"github.com/yusing/go-proxy/internal/notif"
+	"github.com/yusing/go-proxy/internal/utils/strutils"
+)
+
+type (
+	// outlierDetector passively ejects servers on consecutive errors
+	// observed in real traffic, between active health checks.
+	outlierDetector struct {
+		types.OutlierDetectionConfig
+
+		states map[Server]*outlierState
+		mu     sync.Mutex
+	}
+	outlierEvent struct {
+		srv      Server
+		ejected  bool
+		duration time.Duration
+	}
+	outlierState struct {
+		ejected      bool
+		ejectedUntil time.Time
+		// ejections is the number of consecutive ejections, for exponential ejection time.
+		ejections int
+	}
+)
+
+func newOutlierDetector(cfg *types.OutlierDetectionConfig) *outlierDetector {
+	od := &outlierDetector{
+		OutlierDetectionConfig: *cfg,
+		states:                 make(map[Server]*outlierState),
+	}
+	def := types.OutlierDetectionConfigDefault
+	if od.ConsecutiveErrors == 0 {
+		od.ConsecutiveErrors = def.ConsecutiveErrors
+	}
+	if od.BaseEjectionTime == 0 {
+		od.BaseEjectionTime = def.BaseEjectionTime
+	}
+	if od.MaxEjectionTime == 0 {
+		od.MaxEjectionTime = def.MaxEjectionTime
+	}
+	if od.MaxEjectionTime < od.BaseEjectionTime {
+		od.MaxEjectionTime = od.BaseEjectionTime
+	}
+	if od.MaxEjectionPercent == 0 {
+		od.MaxEjectionPercent = def.MaxEjectionPercent
+	}
+	return od
+}
+
+// ejectedServers returns the servers that are currently ejected,
+// ejecting servers with too many consecutive errors
+// and returning servers whose ejection time has passed.
+//
+// At least one server is always left, it returns nil
+// if all servers are ejected, i.e. ejection is ignored in panic mode.
+func (lb *LoadBalancer) ejectedServers(srvs []Server) map[Server]bool {
+	od := lb.outliers
+	if od == nil {
+		return nil
+	}
+
+	var events []outlierEvent
+	defer func() {
+		// notify outside of the lock, notif.Notify may block
+		for _, e := range events {
+			lb.notifyOutlier(e.srv, e.ejected, e.duration)
+		}
+	}()
+
+	od.mu.Lock()
+	defer od.mu.Unlock()
+
+	now := time.Now()
+	ejected := make(map[Server]bool)
+	for _, srv := range srvs {
+		state, ok := od.states[srv]
+		if !ok || !state.ejected {
+			continue
+		}
+		if now.Before(state.ejectedUntil) {
+			ejected[srv] = true
+			continue
+		}
+		state.ejected = false
+		if lb.SlowStart > 0 {
+			srv.StartSlowStart(lb.SlowStart)
+		}
+		events = append(events, outlierEvent{srv: srv})
+	}
+
+	// never eject the last available server
+	maxEjected := min(max(1, len(srvs)*od.MaxEjectionPercent/100), len(srvs)-1)
+	for _, srv := range srvs {
+		if len(ejected) >= maxEjected {
+			break
+		}
+		if ejected[srv] || srv.Stats().ConsecutiveErrors() < int64(od.ConsecutiveErrors) {
+			continue
+		}
+		state, ok := od.states[srv]
+		if !ok {
+			state = new(outlierState)
+			od.states[srv] = state
+		}
+		// forget previous ejections if the server has been fine for a while
+		if now.Sub(state.ejectedUntil) > od.MaxEjectionTime {
+			state.ejections = 0
+		}
+		state.ejections++
+		d := od.BaseEjectionTime << min(state.ejections-1, 30)
+		if d <= 0 || d > od.MaxEjectionTime {
+			d = od.MaxEjectionTime
+		}
+		state.ejected = true
+		state.ejectedUntil = now.Add(d)
+		srv.Stats().ResetConsecutiveErrors()
+		ejected[srv] = true
+		events = append(events, outlierEvent{srv: srv, ejected: true, duration: d})
+	}
+	// servers left turned unhealthy, serve the ejected ones rather than none
+	if len(ejected) > 0 && len(ejected) == len(srvs) {
+		return nil
+	}
+	return ejected
+}
+
+func (lb *LoadBalancer) removeOutlier(srv Server) {
+	if lb.outliers == nil {
+		return
+	}
+	lb.outliers.mu.Lock()
+	defer lb.outliers.mu.Unlock()
+	delete(lb.outliers.states, srv)
+}
+
+func (lb *LoadBalancer) notifyOutlier(srv Server, ejected bool, d time.Duration) {
+	extras := notif.FieldsBody{
+		{Name: "Load Balancer", Value: lb.Link},
+		{Name: "Server", Value: srv.Name()},
+		{Name: "Time", Value: strutils.FormatTime(time.Now())},
+	}
+	if ejected {
+		lb.l.Warn().Str("server", srv.Name()).Dur("duration", d).Msg("server ejected on consecutive errors")
+		extras.Add("Ejected For", strutils.FormatDuration(d))
+		notif.Notify(&notif.LogMessage{
+			Title: "❌ Server ejected ❌",
+			Body:  extras,
+			Color: notif.ColorError,
+		})
+	} else {
+		lb.l.Info().Str("server", srv.Name()).Msg("server returned from ejection")
+		notif.Notify(&notif.LogMessage{
+			Title: "✅ Server returned ✅",
+			Body:  extras,
+			Color: notif.ColorSuccess,
+		})
+	}
+}
+
+// isEjected returns whether the server is currently ejected.
+func (od *outlierDetector) isEjected(srv Server) bool {
+	od.mu.Lock()
+	defer od.mu.Unlock()
+
+	state, ok := od.states[srv]
+	return ok && state.ejected && time.Now().Before(state.ejectedUntil)
+}
@@ -2,21 +2,70 @@ package loadbalancer
 
 import (
 	"net/http"
-	"sync/atomic"
+	"sync"
 )
 
+// roundRobin implements smooth weighted round robin (same as nginx).
+//
+// Servers with equal weights are picked in turn,
+// servers with higher weights are picked more often
+// without sending bursts of consecutive requests to the same server.
 type roundRobin struct {
-	index atomic.Uint32
+	current map[Server]Weight
+	mu      sync.Mutex
 }
 
-func (*LoadBalancer) newRoundRobin() impl        { return &roundRobin{} }
-func (lb *roundRobin) OnAddServer(srv Server)    {}
-func (lb *roundRobin) OnRemoveServer(srv Server) {}
+func (*LoadBalancer) newRoundRobin() impl {
+	return &roundRobin{current: make(map[Server]Weight)}
+}
+
+func (lb *roundRobin) OnAddServer(srv Server) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.current[srv] = 0
+}
+
+func (lb *roundRobin) OnRemoveServer(srv Server) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	delete(lb.current, srv)
+}
 
 func (lb *roundRobin) ServeHTTP(srvs Servers, rw http.ResponseWriter, r *http.Request) {
-	index := lb.index.Add(1) % uint32(len(srvs))
-	srvs[index].ServeHTTP(rw, r)
-	if lb.index.Load() >= 2*uint32(len(srvs)) {
-		lb.index.Store(0)
+	lb.next(srvs).ServeHTTP(rw, r)
+}
+
+func (lb *roundRobin) NextStream(srvs Servers, _ string) (Server, func()) {
+	return lb.next(srvs), nil
+}
+
+func (lb *roundRobin) next(srvs Servers) Server {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	var best Server
+	var bestWeight, total Weight
+	for _, srv := range srvs {
+		w := effectiveWeight(srv)
+		cur := lb.current[srv] + w
+		lb.current[srv] = cur
+		total += w
+		if best == nil || cur > bestWeight {
+			best = srv
+			bestWeight = cur
+		}
+	}
+	lb.current[best] -= total
+	return best
+}
+
+// effectiveWeight returns the weight of the server used for selection.
+//
+// Servers with non-positive weights (or at the beginning of slow start)
+// still receive a minimal share of traffic.
+func effectiveWeight(srv Server) Weight {
+	if w := srv.EffectiveWeight(); w > 0 {
+		return w
 	}
+	return 1
 }
@@ -0,0 +1,284 @@
+package loadbalancer
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/yusing/go-proxy/internal/gperr"
+	"github.com/yusing/go-proxy/internal/net/gphttp/httpheaders"
+	"github.com/yusing/go-proxy/internal/net/gphttp/loadbalancer/types"
+	"github.com/yusing/go-proxy/internal/serialization"
+)
+
+// split splits traffic between variants (versions) of the servers, e.g. `stable` and `canary`.
+//
+// Requests matching a rule go to the variant of the rule.
+// Other clients are assigned a variant by its share of weights,
+// i.e. the sum of weights of its servers, and pinned to it with a cookie.
+//
+// Servers of the same variant are balanced by weighted round robin.
+//
+// Stream connections have no headers or cookies, they are always split by weights.
+type split struct {
+	*LoadBalancer
+	SplitOptions
+
+	rr *roundRobin
+}
+
+type SplitOptions struct {
+	// Rules route matching requests to a variant regardless of weights, the first match wins.
+	Rules []SplitRule `json:"rules"`
+	// Cookie is the name of the cookie pinning a client to a variant.
+	Cookie string `json:"cookie"`
+	// MaxAge of the cookie, a session cookie is used if zero.
+	MaxAge time.Duration `json:"max_age"`
+	// DisableSticky disables pinning clients to a variant,
+	// i.e. every request without a matching rule is split by weights.
+	DisableSticky bool `json:"disable_sticky"`
+}
+
+// SplitRule matches requests by a header or a cookie, e.g. `X-Canary: 1`.
+type SplitRule struct {
+	Header string `json:"header"`
+	Cookie string `json:"cookie"`
+	// Value to match, any non-empty value matches if empty.
+	Value   string `json:"value"`
+	Variant string `json:"variant" validate:"required"`
+}
+
+const splitCookieDefault = "godoxy_variant"
+
+func (lb *LoadBalancer) newSplit() impl {
+	impl := &split{
+		LoadBalancer: lb,
+		rr:           lb.newRoundRobin().(*roundRobin),
+	}
+	if len(lb.Options) > 0 {
+		if err := impl.parseOptions(); err != nil {
+			gperr.LogError("invalid split options, ignoring", err, &impl.l)
+			impl.SplitOptions = SplitOptions{}
+		}
+	}
+	if impl.Cookie == "" {
+		impl.Cookie = splitCookieDefault
+	}
+	return impl
+}
+
+func (impl *split) parseOptions() gperr.Error {
+	if err := serialization.MapUnmarshalValidate(impl.Options, &impl.SplitOptions); err != nil {
+		return err
+	}
+	errs := gperr.NewBuilder("invalid split rules")
+	for i, rule := range impl.Rules {
+		if (rule.Header == "") == (rule.Cookie == "") {
+			errs.Add(gperr.New("exactly one of header and cookie is required").Subjectf("rules[%d]", i))
+		}
+	}
+	return errs.Error()
+}
+
+func (impl *split) OnAddServer(srv Server) {
+	impl.rr.OnAddServer(srv)
+}
+
+func (impl *split) OnRemoveServer(srv Server) {
+	impl.rr.OnRemoveServer(srv)
+}
+
+func (impl *split) ServeHTTP(srvs Servers, rw http.ResponseWriter, r *http.Request) {
+	if variant, ok := impl.matchRule(r); ok {
+		if srvs := variantServers(srvs, variant); len(srvs) > 0 {
+			impl.rr.next(srvs).ServeHTTP(rw, r)
+			return
+		}
+	}
+
+	// variants with zero weight only receive requests matching a rule,
+	// clients pinned to them are reassigned, e.g. after rolling back a canary.
+	weighted := slices.DeleteFunc(slices.Clone(srvs), func(srv Server) bool {
+		return srv.Weight() <= 0
+	})
+	if len(weighted) == 0 {
+		weighted = srvs
+	}
+
+	if !impl.DisableSticky {
+		if cookie, err := r.Cookie(impl.Cookie); err == nil {
+			if srvs := variantServers(weighted, cookie.Value); len(srvs) > 0 {
+				impl.rr.next(srvs).ServeHTTP(rw, r)
+				return
+			}
+		}
+	}
+
+	srv := impl.rr.next(weighted)
+	if !impl.DisableSticky {
+		http.SetCookie(rw, &http.Cookie{
+			Name:     impl.Cookie,
+			Value:    srv.Variant(),
+			Path:     "/",
+			MaxAge:   int(impl.MaxAge.Seconds()),
+			HttpOnly: true,
+			Secure:   r.TLS != nil || r.Header.Get(httpheaders.HeaderXForwardedProto) == "https",
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	srv.ServeHTTP(rw, r)
+}
+
+func (impl *split) NextStream(srvs Servers, srcIP string) (Server, func()) {
+	return impl.rr.NextStream(srvs, srcIP)
+}
+
+// matchRule returns the variant of the first rule matching the request.
+func (impl *split) matchRule(r *http.Request) (variant string, ok bool) {
+	for _, rule := range impl.Rules {
+		var value string
+		if rule.Header != "" {
+			value = r.Header.Get(rule.Header)
+		} else if cookie, err := r.Cookie(rule.Cookie); err == nil {
+			value = cookie.Value
+		}
+		if value == "" {
+			continue
+		}
+		if rule.Value == "" || rule.Value == value {
+			return rule.Variant, true
+		}
+	}
+	return "", false
+}
+
+// variantServers returns the servers of the variant.
+func variantServers(srvs Servers, variant string) Servers {
+	var matched Servers
+	for _, srv := range srvs {
+		if srv.Variant() == variant {
+			matched = append(matched, srv)
+		}
+	}
+	return matched
+}
+
+// Variants returns the sum of weights of servers of each variant.
+func (lb *LoadBalancer) Variants() map[string]Weight {
+	variants := make(map[string]Weight)
+	for _, srv := range lb.pool.Iter {
+		variants[srv.Variant()] += srv.Weight()
+	}
+	return variants
+}
+
+// SetVariantWeights sets the share of weights of each variant at runtime,
+// the weight of a variant is distributed evenly among its servers.
+//
+// Weights must be set for all variants and sum up to 100.
+// They are reapplied when servers are added or removed,
+// and discarded when the variants of the pool change.
+func (lb *LoadBalancer) SetVariantWeights(weights map[string]Weight) gperr.Error {
+	lb.poolMu.Lock()
+	defer lb.poolMu.Unlock()
+
+	variants := lb.serversByVariant()
+
+	errs := gperr.NewBuilder("invalid variant weights")
+	var sum Weight
+	for variant, w := range weights {
+		if _, ok := variants[variant]; !ok {
+			errs.Add(gperr.New("unknown variant").Subject(variant))
+		}
+		if w < 0 {
+			errs.Add(gperr.New("negative weight").Subject(variant))
+		}
+		sum += w
+	}
+	for variant := range variants {
+		if _, ok := weights[variant]; !ok {
+			errs.Add(gperr.New("missing weight").Subject(variant))
+		}
+	}
+	if sum != maxWeight {
+		errs.Add(gperr.Errorf("weights must sum up to %d, got %d", maxWeight, sum))
+	}
+	if err := errs.Error(); err != nil {
+		return err
+	}
+
+	lb.variantWeights = maps.Clone(weights)
+	applyVariantWeights(variants, weights)
+	lb.sumWeight = maxWeight
+
+	lb.l.Info().Interface("weights", weights).Msg("variant weights updated")
+	return nil
+}
+
+// reapplyVariantWeights applies the variant weights set at runtime after the pool has changed,
+// or discards them if the variants of the pool have changed.
+func (lb *LoadBalancer) reapplyVariantWeights() {
+	if lb.variantWeights == nil || lb.pool.Size() == 0 {
+		return
+	}
+	variants := lb.serversByVariant()
+	if len(variants) != len(lb.variantWeights) {
+		lb.discardVariantWeights()
+		return
+	}
+	for variant := range variants {
+		if _, ok := lb.variantWeights[variant]; !ok {
+			lb.discardVariantWeights()
+			return
+		}
+	}
+	applyVariantWeights(variants, lb.variantWeights)
+	lb.sumWeight = maxWeight
+}
+
+func (lb *LoadBalancer) discardVariantWeights() {
+	lb.variantWeights = nil
+	lb.l.Warn().Msg("variants have changed, variant weights set at runtime are discarded")
+}
+
+// serversByVariant returns the servers in the pool grouped by variant.
+func (lb *LoadBalancer) serversByVariant() map[string][]Server {
+	variants := make(map[string][]Server)
+	for _, srv := range lb.pool.Iter {
+		variants[srv.Variant()] = append(variants[srv.Variant()], srv)
+	}
+	return variants
+}
+
+// applyVariantWeights distributes the weight of each variant evenly among its servers.
+func applyVariantWeights(variants map[string][]Server, weights map[string]Weight) {
+	for variant, srvs := range variants {
+		// sort to distribute the remainder deterministically
+		slices.SortFunc(srvs, func(a, b Server) int {
+			return strings.Compare(a.Key(), b.Key())
+		})
+		weightEach := weights[variant] / Weight(len(srvs))
+		remainder := weights[variant] % Weight(len(srvs))
+		for _, srv := range srvs {
+			w := weightEach
+			if remainder > 0 {
+				w++
+				remainder--
+			}
+			srv.SetWeight(w)
+		}
+	}
+}
+
+// VariantStats returns the traffic statistics aggregated by variant.
+func (lb *LoadBalancer) VariantStats() map[string]types.ServerStatsSnapshot {
+	stats := make(map[string]types.ServerStatsSnapshot)
+	for _, srv := range lb.pool.Iter {
+		variant := stats[srv.Variant()]
+		variant.Add(srv.Stats().Snapshot())
+		stats[srv.Variant()] = variant
+	}
+	return stats
+}
@@ -0,0 +1,137 @@
+package loadbalancer
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yusing/go-proxy/internal/gperr"
+	"github.com/yusing/go-proxy/internal/net/gphttp/httpheaders"
+	"github.com/yusing/go-proxy/internal/serialization"
+)
+
+// sticky pins a client to a server with a signed cookie.
+//
+// New clients, and clients whose server has been removed or become unhealthy,
+// are assigned a server by weighted round robin.
+//
+// Stream connections have no cookies, they are always balanced by weighted round robin.
+type sticky struct {
+	*LoadBalancer
+	StickyOptions
+
+	rr  *roundRobin
+	key []byte
+}
+
+type StickyOptions struct {
+	// Cookie is the name of the affinity cookie.
+	Cookie string `json:"cookie"`
+	// MaxAge of the cookie, a session cookie is used if zero.
+	MaxAge time.Duration `json:"max_age"`
+	// Secret is the key to sign the cookie with.
+	//
+	// If empty, a random key is generated, which means
+	// clients are reassigned after restart.
+	Secret string `json:"secret"`
+}
+
+const stickyCookieDefault = "godoxy_sticky"
+
+func (lb *LoadBalancer) newSticky() impl {
+	impl := &sticky{
+		LoadBalancer: lb,
+		rr:           lb.newRoundRobin().(*roundRobin),
+	}
+	if len(lb.Options) > 0 {
+		if err := serialization.MapUnmarshalValidate(lb.Options, &impl.StickyOptions); err != nil {
+			gperr.LogError("invalid sticky options, ignoring", err, &impl.l)
+			impl.StickyOptions = StickyOptions{}
+		}
+	}
+	if impl.Cookie == "" {
+		impl.Cookie = stickyCookieDefault
+	}
+	if impl.Secret != "" {
+		impl.key = []byte(impl.Secret)
+	} else {
+		impl.key = make([]byte, 32)
+		_, _ = rand.Read(impl.key)
+	}
+	return impl
+}
+
+func (impl *sticky) OnAddServer(srv Server) {
+	impl.rr.OnAddServer(srv)
+}
+
+func (impl *sticky) OnRemoveServer(srv Server) {
+	impl.rr.OnRemoveServer(srv)
+}
+
+func (impl *sticky) ServeHTTP(srvs Servers, rw http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(impl.Cookie); err == nil {
+		if name, ok := impl.verify(cookie.Value); ok {
+			for _, srv := range srvs {
+				if srv.Name() == name {
+					srv.ServeHTTP(rw, r)
+					return
+				}
+			}
+		}
+	}
+
+	srv := impl.rr.next(srvs)
+	http.SetCookie(rw, &http.Cookie{
+		Name:     impl.Cookie,
+		Value:    impl.sign(srv.Name()),
+		Path:     "/",
+		MaxAge:   int(impl.MaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get(httpheaders.HeaderXForwardedProto) == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+	srv.ServeHTTP(rw, r)
+}
+
+func (impl *sticky) NextStream(srvs Servers, srcIP string) (Server, func()) {
+	return impl.rr.NextStream(srvs, srcIP)
+}
+
+// sign returns the cookie value for the server name, in the form of `<base64 name>.<hex hmac>`.
+func (impl *sticky) sign(name string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(name)) + "." + hex.EncodeToString(impl.mac(name))
+}
+
+func (impl *sticky) verify(value string) (name string, ok bool) {
+	encName, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return "", false
+	}
+	b, err := base64.RawURLEncoding.DecodeString(encName)
+	if err != nil {
+		return "", false
+	}
+	mac, err := hex.DecodeString(sig)
+	if err != nil {
+		return "", false
+	}
+	name = string(b)
+	if !hmac.Equal(mac, impl.mac(name)) {
+		return "", false
+	}
+	return name, true
+}
+
+func (impl *sticky) mac(name string) []byte {
+	h := hmac.New(sha256.New, impl.key)
+	h.Write([]byte(impl.Link))
+	h.Write([]byte{0})
+	h.Write([]byte(name))
+	return h.Sum(nil)
+}
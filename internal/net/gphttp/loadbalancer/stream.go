@@ -0,0 +1,29 @@
+package loadbalancer
+
+import (
+	"github.com/yusing/go-proxy/internal/gperr"
+)
+
+var ErrNoServerAvailable = gperr.New("no server available")
+
+// NextStreamServer selects a healthy server for a new stream connection from srcIP.
+//
+// release must be called when the connection is closed,
+// with the error if the connection to the server could not be established.
+func (lb *LoadBalancer) NextStreamServer(srcIP string) (srv Server, release func(err error), err error) {
+	srvs := lb.availServers()
+	if len(srvs) == 0 {
+		return nil, nil, ErrNoServerAvailable
+	}
+	srv, implRelease := lb.impl.NextStream(srvs, srcIP)
+	if srv == nil {
+		return nil, nil, ErrNoServerAvailable
+	}
+	done := srv.Stats().BeginConn()
+	return srv, func(err error) {
+		if implRelease != nil {
+			implRelease()
+		}
+		done(err != nil)
+	}, nil
+}
@@ -1,8 +1,65 @@
 package types
 
-type Config struct {
-	Link    string         `json:"link"`
-	Mode    Mode           `json:"mode"`
-	Weight  Weight         `json:"weight"`
-	Options map[string]any `json:"options,omitempty"`
+import "time"
+
+type (
+	Config struct {
+		Link   string `json:"link"`
+		Mode   Mode   `json:"mode"`
+		Weight Weight `json:"weight"`
+		// Priority of the server, backup servers only receive traffic
+		// when all primary servers are unhealthy.
+		Priority Priority `json:"priority,omitempty" validate:"omitempty,oneof=primary backup"`
+		// Zone of the server, defaults to the agent name for routes on agents, otherwise `local`.
+		Zone string `json:"zone,omitempty"`
+		// Variant is the version of the server for the split mode, e.g. `stable` or `canary`,
+		// defaults to `stable`.
+		Variant string `json:"variant,omitempty"`
+		// Locality prefers servers in the local zone.
+		Locality *LocalityConfig `json:"locality,omitempty"`
+		// OutlierDetection ejects servers on consecutive errors observed in real traffic.
+		OutlierDetection *OutlierDetectionConfig `json:"outlier_detection,omitempty"`
+		// SlowStart ramps the weight of a server from zero to its weight over this duration
+		// after it is added, turns healthy or returns from ejection.
+		SlowStart time.Duration  `json:"slow_start,omitempty"`
+		Options   map[string]any `json:"options,omitempty"`
+	}
+	Priority string
+
+	// LocalityConfig prefers servers in the local zone,
+	// spilling over to other zones when no local server is available or local servers are overloaded.
+	LocalityConfig struct {
+		// LocalZone defaults to `local`.
+		LocalZone string `json:"local_zone"`
+		// MaxActive is the average number of in-flight requests (or connections) per local server
+		// to start spilling over to other zones, 0 to spill over only when no local server is available.
+		MaxActive int `json:"max_active" validate:"omitempty,gte=0"`
+	}
+	OutlierDetectionConfig struct {
+		// ConsecutiveErrors is the number of consecutive 5xx responses or connection errors to eject a server.
+		ConsecutiveErrors int `json:"consecutive_errors" validate:"omitempty,gte=1"`
+		// BaseEjectionTime is doubled on each consecutive ejection of the same server, up to MaxEjectionTime.
+		BaseEjectionTime time.Duration `json:"base_ejection_time"`
+		MaxEjectionTime  time.Duration `json:"max_ejection_time"`
+		// MaxEjectionPercent is the maximum percentage of servers ejected at the same time,
+		// at least one server can always be ejected.
+		MaxEjectionPercent int `json:"max_ejection_percent" validate:"omitempty,gte=0,lte=100"`
+	}
+)
+
+var OutlierDetectionConfigDefault = OutlierDetectionConfig{
+	ConsecutiveErrors:  5,
+	BaseEjectionTime:   30 * time.Second,
+	MaxEjectionTime:    5 * time.Minute,
+	MaxEjectionPercent: 50,
 }
+
+const (
+	ZoneLocal      = "local"
+	VariantDefault = "stable"
+)
+
+const (
+	PriorityPrimary Priority = "primary"
+	PriorityBackup  Priority = "backup"
+)
@@ -7,10 +7,15 @@ import (
 type Mode string
 
 const (
-	ModeUnset      Mode = ""
-	ModeRoundRobin Mode = "roundrobin"
-	ModeLeastConn  Mode = "leastconn"
-	ModeIPHash     Mode = "iphash"
+	ModeUnset        Mode = ""
+	ModeRoundRobin   Mode = "roundrobin"
+	ModeLeastConn    Mode = "leastconn"
+	ModeIPHash       Mode = "iphash"
+	ModeSticky       Mode = "sticky"
+	ModeLeastLatency Mode = "leastlatency"
+	ModeP2C          Mode = "p2c"
+	ModeHash         Mode = "hash"
+	ModeSplit        Mode = "split"
 )
 
 func (mode *Mode) ValidateUpdate() bool {
@@ -26,6 +31,21 @@ func (mode *Mode) ValidateUpdate() bool {
 	case string(ModeIPHash):
 		*mode = ModeIPHash
 		return true
+	case string(ModeSticky):
+		*mode = ModeSticky
+		return true
+	case string(ModeLeastLatency):
+		*mode = ModeLeastLatency
+		return true
+	case string(ModeP2C):
+		*mode = ModeP2C
+		return true
+	case string(ModeHash):
+		*mode = ModeHash
+		return true
+	case string(ModeSplit):
+		*mode = ModeSplit
+		return true
 	}
 	*mode = ModeRoundRobin
 	return false
@@ -2,8 +2,11 @@ package types
 
 import (
 	"net/http"
+	"sync/atomic"
+	"time"
 
 	idlewatcher "github.com/yusing/go-proxy/internal/idlewatcher/types"
+	gphttp "github.com/yusing/go-proxy/internal/net/gphttp"
 	net "github.com/yusing/go-proxy/internal/net/types"
 	U "github.com/yusing/go-proxy/internal/utils"
 	"github.com/yusing/go-proxy/internal/watcher/health"
@@ -13,9 +16,16 @@ type (
 	server struct {
 		_ U.NoCopy
 
-		name   string
-		url    *net.URL
-		weight Weight
+		name     string
+		url      *net.URL
+		weight   atomic.Int64
+		priority Priority
+		zone     string
+		variant  string
+		stats    ServerStats
+
+		slowStart      atomic.Int64 // duration of the slow start window
+		slowStartSince atomic.Int64 // unix nano
 
 		http.Handler `json:"-"`
 		health.HealthMonitor
@@ -28,27 +38,51 @@ type (
 		Key() string
 		URL() *net.URL
 		Weight() Weight
+		// SetWeight updates the weight of the server, it is safe to call at runtime.
 		SetWeight(weight Weight)
+		Priority() Priority
+		Zone() string
+		Variant() string
+		// EffectiveWeight returns the weight ramped up during slow start.
+		EffectiveWeight() Weight
+		// StartSlowStart ramps the effective weight from zero to the weight over d.
+		StartSlowStart(d time.Duration)
 		TryWake() error
+		Stats() *ServerStats
 	}
 )
 
-func NewServer(name string, url *net.URL, weight Weight, handler http.Handler, healthMon health.HealthMonitor) Server {
+func NewServer(name string, url *net.URL, weight Weight, priority Priority, zone, variant string, handler http.Handler, healthMon health.HealthMonitor) Server {
+	if priority == "" {
+		priority = PriorityPrimary
+	}
+	if zone == "" {
+		zone = ZoneLocal
+	}
+	if variant == "" {
+		variant = VariantDefault
+	}
 	srv := &server{
 		name:          name,
 		url:           url,
-		weight:        weight,
+		priority:      priority,
+		zone:          zone,
+		variant:       variant,
 		Handler:       handler,
 		HealthMonitor: healthMon,
 	}
+	srv.SetWeight(weight)
 	return srv
 }
 
 func TestNewServer[T ~int | ~float32 | ~float64](weight T) Server {
 	srv := &server{
-		weight: Weight(weight),
-		url:    net.MustParseURL("http://localhost"),
+		url:      net.MustParseURL("http://localhost"),
+		priority: PriorityPrimary,
+		zone:     ZoneLocal,
+		variant:  VariantDefault,
 	}
+	srv.SetWeight(Weight(weight))
 	return srv
 }
 
@@ -60,16 +94,61 @@ func (srv *server) URL() *net.URL {
 	return srv.url
 }
 
+// Key returns the host and port of the server, or the socket path for unix socket servers.
 func (srv *server) Key() string {
-	return srv.url.Host
+	return srv.url.Host + srv.url.Path
 }
 
 func (srv *server) Weight() Weight {
-	return srv.weight
+	return Weight(srv.weight.Load())
 }
 
 func (srv *server) SetWeight(weight Weight) {
-	srv.weight = weight
+	srv.weight.Store(int64(weight))
+}
+
+func (srv *server) EffectiveWeight() Weight {
+	w := srv.Weight()
+	d := srv.slowStart.Load()
+	if d <= 0 {
+		return w
+	}
+	elapsed := time.Now().UnixNano() - srv.slowStartSince.Load()
+	if elapsed >= d {
+		return w
+	}
+	return Weight(int64(w) * elapsed / d)
+}
+
+func (srv *server) StartSlowStart(d time.Duration) {
+	srv.slowStartSince.Store(time.Now().UnixNano())
+	srv.slowStart.Store(int64(d))
+}
+
+func (srv *server) Priority() Priority {
+	return srv.priority
+}
+
+func (srv *server) Zone() string {
+	return srv.zone
+}
+
+func (srv *server) Variant() string {
+	return srv.variant
+}
+
+func (srv *server) Stats() *ServerStats {
+	return &srv.stats
+}
+
+// ServeHTTP implements http.Handler.
+func (srv *server) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	done := srv.stats.begin(r)
+	w := gphttp.NewModifyResponseWriter(rw, r, nil)
+	defer func() {
+		done(w.StatusCode(), int64(w.Size()))
+	}()
+	srv.Handler.ServeHTTP(w, r)
 }
 
 func (srv *server) String() string {
@@ -0,0 +1,252 @@
+package types
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// ServerStats is the traffic statistics of a load balanced server.
+	ServerStats struct {
+		requests atomic.Int64
+		active   atomic.Int64
+		errors   atomic.Int64
+		// consecutiveErrors is reset on a successful request.
+		consecutiveErrors atomic.Int64
+
+		bytesIn  atomic.Int64
+		bytesOut atomic.Int64
+
+		// statusCodes counts responses by status class, e.g. statusCodes[2] for 2xx.
+		statusCodes [6]atomic.Int64
+
+		latencySum atomic.Int64 // in microseconds
+		latency    [len(latencyBuckets) + 1]atomic.Int64
+
+		ewma ewma
+	}
+	// ewma is an exponentially weighted moving average of response times
+	// with time based decay.
+	ewma struct {
+		mu    sync.Mutex
+		value float64 // in nanoseconds
+		last  time.Time
+	}
+	ServerStatsSnapshot struct {
+		// Requests is the number of requests, or connections for stream routes.
+		Requests int64 `json:"requests"`
+		// Active is the number of in-flight requests.
+		Active int64 `json:"active"`
+		// Errors is the number of 5xx responses, including proxy errors.
+		Errors int64 `json:"errors"`
+		// StatusCodes is the number of responses by status class, e.g. `2xx`.
+		StatusCodes map[string]int64 `json:"status_codes"`
+		BytesIn     int64            `json:"bytes_in"`
+		BytesOut    int64            `json:"bytes_out"`
+		// AvgLatency is the average response time in milliseconds.
+		AvgLatency float64 `json:"avg_latency"`
+		// EWMALatency is the exponentially weighted response time in milliseconds.
+		EWMALatency float64         `json:"ewma_latency"`
+		Latency     []LatencyBucket `json:"latency"`
+	}
+	// LatencyBucket is a cumulative histogram bucket.
+	// Count is the number of requests completed within LE milliseconds.
+	//
+	// LE is 0 for the +Inf bucket.
+	LatencyBucket struct {
+		LE    int64 `json:"le"`
+		Count int64 `json:"count"`
+	}
+)
+
+const (
+	// ewmaDecay is the time constant of the EWMA,
+	// samples older than this have ~37% of their original weight.
+	ewmaDecay = 10 * time.Second
+	// ewmaOutlierFactor caps a single sample to this factor of the current average,
+	// so one slow request does not take a server out of rotation.
+	ewmaOutlierFactor = 4
+)
+
+// latencyBuckets are the upper bounds of the latency histogram in milliseconds.
+var latencyBuckets = [...]int64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// begin marks the start of a request and returns a function to record its result.
+func (s *ServerStats) begin(r *http.Request) (done func(status int, bytesOut int64)) {
+	start := time.Now()
+	s.requests.Add(1)
+	s.active.Add(1)
+	if r.ContentLength > 0 {
+		s.bytesIn.Add(r.ContentLength)
+	}
+	return func(status int, bytesOut int64) {
+		s.active.Add(-1)
+		s.recordResult(status >= http.StatusInternalServerError)
+		s.recordStatus(status)
+		s.bytesOut.Add(bytesOut)
+		latency := time.Since(start)
+		s.observeLatency(latency)
+		s.ewma.observe(latency)
+	}
+}
+
+// BeginConn marks the start of a stream connection and returns a function to record its result.
+func (s *ServerStats) BeginConn() (done func(failed bool)) {
+	s.requests.Add(1)
+	s.active.Add(1)
+	return func(failed bool) {
+		s.active.Add(-1)
+		s.recordResult(failed)
+	}
+}
+
+func (s *ServerStats) recordResult(failed bool) {
+	if failed {
+		s.errors.Add(1)
+		s.consecutiveErrors.Add(1)
+	} else {
+		s.consecutiveErrors.Store(0)
+	}
+}
+
+func (s *ServerStats) recordStatus(status int) {
+	if class := status / 100; class > 0 && class < len(s.statusCodes) {
+		s.statusCodes[class].Add(1)
+	}
+}
+
+// ConsecutiveErrors returns the number of failed requests or connections since the last successful one.
+func (s *ServerStats) ConsecutiveErrors() int64 {
+	return s.consecutiveErrors.Load()
+}
+
+// ResetConsecutiveErrors resets the consecutive error count, e.g. after the server is ejected.
+func (s *ServerStats) ResetConsecutiveErrors() {
+	s.consecutiveErrors.Store(0)
+}
+
+func (s *ServerStats) observeLatency(d time.Duration) {
+	s.latencySum.Add(d.Microseconds())
+	ms := d.Milliseconds()
+	for i, le := range latencyBuckets {
+		if ms <= le {
+			s.latency[i].Add(1)
+			return
+		}
+	}
+	s.latency[len(latencyBuckets)].Add(1)
+}
+
+// Active returns the number of in-flight requests or connections.
+func (s *ServerStats) Active() int64 {
+	return s.active.Load()
+}
+
+// EWMALatency returns the exponentially weighted response time.
+//
+// It decays towards zero while the server receives no traffic,
+// so slow servers are retried eventually.
+func (s *ServerStats) EWMALatency() time.Duration {
+	return s.ewma.load()
+}
+
+func (e *ewma) observe(d time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	sample := float64(d)
+	if e.last.IsZero() {
+		e.value = sample
+		e.last = now
+		return
+	}
+	if e.value > 0 && sample > e.value*ewmaOutlierFactor {
+		sample = e.value * ewmaOutlierFactor
+	}
+	w := math.Exp(-float64(now.Sub(e.last)) / float64(ewmaDecay))
+	e.value = e.value*w + sample*(1-w)
+	e.last = now
+}
+
+func (e *ewma) load() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.last.IsZero() {
+		return 0
+	}
+	idle := time.Since(e.last)
+	return time.Duration(e.value * math.Exp(-float64(idle)/float64(ewmaDecay)))
+}
+
+func (s *ServerStats) Snapshot() ServerStatsSnapshot {
+	snapshot := ServerStatsSnapshot{
+		Requests:    s.requests.Load(),
+		Active:      s.active.Load(),
+		Errors:      s.errors.Load(),
+		BytesIn:     s.bytesIn.Load(),
+		BytesOut:    s.bytesOut.Load(),
+		EWMALatency: float64(s.EWMALatency().Microseconds()) / 1000,
+		StatusCodes: make(map[string]int64),
+		Latency:     make([]LatencyBucket, 0, len(s.latency)),
+	}
+	for class := 1; class < len(s.statusCodes); class++ {
+		if n := s.statusCodes[class].Load(); n > 0 {
+			snapshot.StatusCodes[strconv.Itoa(class)+"xx"] = n
+		}
+	}
+	var count int64
+	for i := range s.latency {
+		count += s.latency[i].Load()
+		var le int64
+		if i < len(latencyBuckets) {
+			le = latencyBuckets[i]
+		}
+		snapshot.Latency = append(snapshot.Latency, LatencyBucket{LE: le, Count: count})
+	}
+	if count > 0 {
+		snapshot.AvgLatency = float64(s.latencySum.Load()) / float64(count) / 1000
+	}
+	return snapshot
+}
+
+// Add adds the counters of other to s, e.g. to aggregate the stats of servers in a zone.
+func (s *ServerStatsSnapshot) Add(other ServerStatsSnapshot) {
+	count, otherCount := s.count(), other.count()
+	if total := count + otherCount; total > 0 {
+		s.AvgLatency = (s.AvgLatency*float64(count) + other.AvgLatency*float64(otherCount)) / float64(total)
+		s.EWMALatency = (s.EWMALatency*float64(count) + other.EWMALatency*float64(otherCount)) / float64(total)
+	}
+	s.Requests += other.Requests
+	s.Active += other.Active
+	s.Errors += other.Errors
+	s.BytesIn += other.BytesIn
+	s.BytesOut += other.BytesOut
+	if s.StatusCodes == nil {
+		s.StatusCodes = make(map[string]int64, len(other.StatusCodes))
+	}
+	for class, n := range other.StatusCodes {
+		s.StatusCodes[class] += n
+	}
+	if len(s.Latency) == 0 {
+		s.Latency = make([]LatencyBucket, len(other.Latency))
+		copy(s.Latency, other.Latency)
+		return
+	}
+	for i := range min(len(s.Latency), len(other.Latency)) {
+		s.Latency[i].Count += other.Latency[i].Count
+	}
+}
+
+// count returns the number of completed requests.
+func (s *ServerStatsSnapshot) count() int64 {
+	if len(s.Latency) == 0 {
+		return 0
+	}
+	return s.Latency[len(s.Latency)-1].Count
+}
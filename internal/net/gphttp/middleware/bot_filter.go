@@ -0,0 +1,299 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/yusing/go-proxy/internal/common"
+	"github.com/yusing/go-proxy/internal/gperr"
+	gphttp "github.com/yusing/go-proxy/internal/net/gphttp"
+	"github.com/yusing/go-proxy/internal/net/gphttp/httpheaders"
+)
+
+type (
+	botFilter struct {
+		BotFilterOpts
+
+		categories []*botCategory
+		rdnsCache  *ttlCache[bool]
+	}
+	BotFilterOpts struct {
+		// Categories maps a category name to its config.
+		//
+		// User agent patterns of a category are loaded from
+		// `config/middlewares/botfilter/<category>.txt` (or `file` if set),
+		// one case-insensitive substring per line, `#` for comments.
+		Categories map[string]BotCategoryConfig `validate:"min=1"`
+	}
+	BotCategoryConfig struct {
+		Action     BotAction `json:"action"`
+		UserAgents []string  `json:"user_agents"`
+		// File overrides the default list file, relative to `config/middlewares/botfilter`.
+		File string `json:"file"`
+		// VerifyRDNS is a list of domain suffixes, e.g. `googlebot.com`.
+		//
+		// If set, a matching request is only treated as this category if
+		// the reverse DNS of the client IP ends with one of the suffixes
+		// and resolves back to the same IP. Otherwise UnverifiedAction is applied.
+		VerifyRDNS       []string      `json:"verify_rdns"`
+		UnverifiedAction BotAction     `json:"unverified_action"`
+		StatusCode       int           `json:"status_code" aliases:"status"`
+		Message          string        `json:"message"`
+		TarpitDelay      time.Duration `json:"tarpit_delay"`
+		Robots           string        `json:"robots"`
+	}
+	BotAction string
+
+	botCategory struct {
+		name string
+		BotCategoryConfig
+		patterns []string
+	}
+)
+
+const (
+	BotActionAllow  BotAction = "allow"
+	BotActionBlock  BotAction = "block"
+	BotActionTarpit BotAction = "tarpit"
+	// BotActionRobots serves a disallow-all `robots.txt` to the category
+	// and lets other requests pass through.
+	BotActionRobots BotAction = "robots"
+)
+
+const (
+	botFilterListBasePath = common.MiddlewareComposeBasePath + "/botfilter"
+	botFilterRobotsPath   = "/robots.txt"
+	botFilterRDNSCacheTTL = time.Hour
+	botFilterRDNSCacheMax = 10000
+	botFilterRDNSTimeout  = 3 * time.Second
+)
+
+var ErrInvalidBotAction = gperr.New("invalid bot action")
+
+var (
+	BotFilter                = NewMiddleware[botFilter]()
+	botCategoryConfigDefault = BotCategoryConfig{
+		Action:           BotActionBlock,
+		UnverifiedAction: BotActionBlock,
+		StatusCode:       http.StatusForbidden,
+		Message:          "Forbidden",
+		TarpitDelay:      30 * time.Second,
+		Robots:           "User-agent: *\nDisallow: /\n",
+	}
+)
+
+// setup implements MiddlewareWithSetup.
+func (bf *botFilter) setup() {
+	bf.rdnsCache = newTTLCache[bool](botFilterRDNSCacheTTL, botFilterRDNSCacheMax)
+}
+
+// finalize implements MiddlewareFinalizerWithError.
+func (bf *botFilter) finalize() error {
+	names := make([]string, 0, len(bf.Categories))
+	for name := range bf.Categories {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	errs := gperr.NewBuilder("botfilter")
+	bf.categories = make([]*botCategory, 0, len(names))
+	for _, name := range names {
+		cat := &botCategory{name: name, BotCategoryConfig: bf.Categories[name]}
+		cfg := &cat.BotCategoryConfig
+		applyBotCategoryDefaults(cfg)
+		for _, ua := range cfg.UserAgents {
+			if ua = strings.ToLower(strings.TrimSpace(ua)); ua != "" {
+				cat.patterns = append(cat.patterns, ua)
+			}
+		}
+
+		file := cfg.File
+		if file == "" {
+			file = name + ".txt"
+		}
+		patterns, err := loadBotUserAgentList(path.Join(botFilterListBasePath, file))
+		switch {
+		case err == nil:
+			cat.patterns = append(cat.patterns, patterns...)
+		case errors.Is(err, os.ErrNotExist) && cfg.File == "":
+			// default list file is optional
+		default:
+			errs.Add(gperr.Wrap(err).Subject(name))
+			continue
+		}
+
+		if len(cat.patterns) == 0 {
+			errs.Add(gperr.New("no user agent patterns").Subject(name))
+			continue
+		}
+		bf.categories = append(bf.categories, cat)
+	}
+	return errs.Error()
+}
+
+// Validate implements serialization.CustomValidator.
+func (cfg *BotCategoryConfig) Validate() gperr.Error {
+	errs := gperr.NewBuilder()
+	for _, action := range []BotAction{cfg.Action, cfg.UnverifiedAction} {
+		switch action {
+		case "", BotActionAllow, BotActionBlock, BotActionTarpit, BotActionRobots:
+		default:
+			errs.Add(ErrInvalidBotAction.Subject(string(action)))
+		}
+	}
+	if cfg.StatusCode != 0 && !gphttp.IsStatusCodeValid(cfg.StatusCode) {
+		errs.Addf("invalid status code %d", cfg.StatusCode)
+	}
+	return errs.Error()
+}
+
+func applyBotCategoryDefaults(cfg *BotCategoryConfig) {
+	if cfg.Action == "" {
+		cfg.Action = botCategoryConfigDefault.Action
+	}
+	if cfg.UnverifiedAction == "" {
+		cfg.UnverifiedAction = botCategoryConfigDefault.UnverifiedAction
+	}
+	if cfg.StatusCode == 0 {
+		cfg.StatusCode = botCategoryConfigDefault.StatusCode
+	}
+	if cfg.Message == "" {
+		cfg.Message = botCategoryConfigDefault.Message
+	}
+	if cfg.TarpitDelay == 0 {
+		cfg.TarpitDelay = botCategoryConfigDefault.TarpitDelay
+	}
+	if cfg.Robots == "" {
+		cfg.Robots = botCategoryConfigDefault.Robots
+	}
+}
+
+func loadBotUserAgentList(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if line = strings.ToLower(strings.TrimSpace(line)); line != "" {
+			patterns = append(patterns, line)
+		}
+	}
+	return patterns, scanner.Err()
+}
+
+// before implements RequestModifier.
+func (bf *botFilter) before(w http.ResponseWriter, r *http.Request) (proceed bool) {
+	cat := bf.classify(r.UserAgent())
+	if cat == nil {
+		return true
+	}
+	action := cat.Action
+	if len(cat.VerifyRDNS) > 0 && !bf.verifyRDNS(r, cat.VerifyRDNS) {
+		action = cat.UnverifiedAction
+	}
+	return cat.apply(action, w, r)
+}
+
+func (bf *botFilter) classify(ua string) *botCategory {
+	if ua == "" {
+		return nil
+	}
+	ua = strings.ToLower(ua)
+	for _, cat := range bf.categories {
+		for _, p := range cat.patterns {
+			if strings.Contains(ua, p) {
+				return cat
+			}
+		}
+	}
+	return nil
+}
+
+func (cat *botCategory) apply(action BotAction, w http.ResponseWriter, r *http.Request) (proceed bool) {
+	switch action {
+	case BotActionAllow:
+		return true
+	case BotActionRobots:
+		if r.URL.Path != botFilterRobotsPath {
+			return true
+		}
+		w.Header().Set(httpheaders.HeaderContentType, "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(cat.Robots))
+		return false
+	case BotActionTarpit:
+		timer := time.NewTimer(cat.TarpitDelay)
+		defer timer.Stop()
+		select {
+		case <-r.Context().Done():
+			return false
+		case <-timer.C:
+		}
+	}
+	http.Error(w, cat.Message, cat.StatusCode)
+	return false
+}
+
+// verifyRDNS checks whether the client IP reverse resolves to one of the domain suffixes,
+// and the resolved hostname resolves back to the client IP.
+func (bf *botFilter) verifyRDNS(r *http.Request, suffixes []string) bool {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	key := ip + "|" + strings.Join(suffixes, ",")
+	if ok, cached := bf.rdnsCache.Load(key); cached {
+		return ok
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), botFilterRDNSTimeout)
+	defer cancel()
+
+	ok := lookupRDNS(ctx, ip, suffixes)
+	// do not cache lookups cut short by the client going away or a timeout
+	if ctx.Err() == nil {
+		bf.rdnsCache.Store(key, ok)
+	}
+	return ok
+}
+
+func lookupRDNS(ctx context.Context, ip string, suffixes []string) bool {
+	names, err := net.DefaultResolver.LookupAddr(ctx, ip)
+	if err != nil {
+		return false
+	}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSuffix(name, "."))
+		if !slices.ContainsFunc(suffixes, func(suffix string) bool {
+			suffix = strings.ToLower(strings.TrimPrefix(suffix, "."))
+			return name == suffix || strings.HasSuffix(name, "."+suffix)
+		}) {
+			continue
+		}
+		addrs, err := net.DefaultResolver.LookupHost(ctx, name)
+		if err != nil {
+			continue
+		}
+		if slices.Contains(addrs, ip) {
+			return true
+		}
+	}
+	return false
+}
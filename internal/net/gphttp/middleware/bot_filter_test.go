@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/yusing/go-proxy/internal/net/types"
+	. "github.com/yusing/go-proxy/internal/utils/testing"
+)
+
+func TestBotFilter(t *testing.T) {
+	bf, err := BotFilter.New(OptionsRaw{
+		"categories": map[string]any{
+			"ai": map[string]any{
+				"user_agents": []string{"GPTBot", "ClaudeBot"},
+				"message":     "no crawlers",
+			},
+			"seo": map[string]any{
+				"action":      "robots",
+				"user_agents": []string{"AhrefsBot"},
+			},
+			"search": map[string]any{
+				"action":      "allow",
+				"user_agents": []string{"Googlebot"},
+			},
+		},
+	})
+	ExpectNoError(t, err)
+
+	tests := []struct {
+		name   string
+		ua     string
+		path   string
+		status int
+		body   string
+	}{
+		{"normal", "Mozilla/5.0", "/", http.StatusOK, ""},
+		{"block", "Mozilla/5.0 (compatible; GPTBot/1.0)", "/", http.StatusForbidden, "no crawlers"},
+		{"case_insensitive", "claudebot", "/", http.StatusForbidden, "no crawlers"},
+		{"robots_txt", "AhrefsBot/7.0", "/robots.txt", http.StatusOK, "Disallow: /"},
+		{"robots_other", "AhrefsBot/7.0", "/", http.StatusOK, ""},
+		{"allow", "Googlebot/2.1", "/", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := newMiddlewareTest(bf, &testArgs{
+				reqURL:  Must(types.ParseURL("https://example.com" + tt.path)),
+				headers: http.Header{"User-Agent": {tt.ua}},
+			})
+			ExpectNoError(t, err)
+			ExpectEqual(t, result.ResponseStatus, tt.status)
+			if tt.body != "" {
+				ExpectTrue(t, strings.Contains(string(result.Data), tt.body))
+			}
+		})
+	}
+}
+
+func TestBotFilterValidation(t *testing.T) {
+	_, err := BotFilter.New(OptionsRaw{
+		"categories": map[string]any{
+			"ai": map[string]any{
+				"action":      "drop",
+				"user_agents": []string{"GPTBot"},
+			},
+		},
+	})
+	ExpectError(t, ErrInvalidBotAction, err)
+
+	_, err = BotFilter.New(OptionsRaw{
+		"categories": map[string]any{
+			"nothing": map[string]any{},
+		},
+	})
+	ExpectHasError(t, err)
+}
@@ -1,7 +1,10 @@
 package errorpage
 
 import (
+	"bytes"
 	"fmt"
+	"html"
+	"net/http"
 	"os"
 	"path"
 	"sync"
@@ -9,6 +12,7 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/yusing/go-proxy/internal/common"
 	"github.com/yusing/go-proxy/internal/gperr"
+	"github.com/yusing/go-proxy/internal/net/gphttp/httpheaders"
 	"github.com/yusing/go-proxy/internal/task"
 	U "github.com/yusing/go-proxy/internal/utils"
 	F "github.com/yusing/go-proxy/internal/utils/functional"
@@ -16,7 +20,12 @@ import (
 	"github.com/yusing/go-proxy/internal/watcher/events"
 )
 
-const errPagesBasePath = common.ErrorPagesBasePath
+const (
+	errPagesBasePath = common.ErrorPagesBasePath
+
+	// VarRequestID in error pages is replaced with the request ID.
+	VarRequestID = "$request_id"
+)
 
 var (
 	setupOnce      sync.Once
@@ -45,6 +54,15 @@ func GetErrorPageByStatus(statusCode int) (content []byte, ok bool) {
 	return
 }
 
+// WithRequestID replaces VarRequestID in the error page with the HTML escaped request ID of r.
+func WithRequestID(content []byte, r *http.Request) []byte {
+	if r == nil || !bytes.Contains(content, []byte(VarRequestID)) {
+		return content
+	}
+	id := html.EscapeString(httpheaders.RequestID(r))
+	return bytes.ReplaceAll(content, []byte(VarRequestID), []byte(id))
+}
+
 func loadContent() {
 	files, err := U.ListFiles(errPagesBasePath, 0)
 	if err != nil {
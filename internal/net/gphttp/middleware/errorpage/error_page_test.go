@@ -0,0 +1,16 @@
+package errorpage
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/yusing/go-proxy/internal/net/gphttp/httpheaders"
+	. "github.com/yusing/go-proxy/internal/utils/testing"
+)
+
+func TestWithRequestIDEscaped(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(httpheaders.HeaderXRequestID, "<script>alert(1)</script>")
+	page := WithRequestID([]byte("<p>request id: "+VarRequestID+"</p>"), req)
+	ExpectEqual(t, string(page), "<p>request id: &lt;script&gt;alert(1)&lt;/script&gt;</p>")
+}
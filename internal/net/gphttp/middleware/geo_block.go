@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/yusing/go-proxy/internal/acl"
+	"github.com/yusing/go-proxy/internal/gperr"
+	"github.com/yusing/go-proxy/internal/maxmind"
+	"github.com/yusing/go-proxy/internal/net/gphttp/httpheaders"
+	"github.com/yusing/go-proxy/internal/net/gphttp/middleware/errorpage"
+)
+
+type (
+	geoBlock struct {
+		GeoBlockOpts
+
+		defaultAllow bool
+		allowLocal   bool
+		cache        *ttlCache[bool]
+	}
+	// GeoBlockOpts uses the same matcher syntax as the global ACL,
+	// e.g. `country:GB`, `tz:Asia/Shanghai`, `cidr:1.2.3.0/24`.
+	GeoBlockOpts struct {
+		// Default is the action when no rule matches.
+		//
+		// Defaults to deny when only allow rules are set, otherwise allow.
+		Default    string       `json:"default" validate:"omitempty,oneof=allow deny"`
+		AllowLocal *bool        `json:"allow_local"` // default: true
+		Allow      acl.Matchers `json:"allow"`
+		Deny       acl.Matchers `json:"deny"`
+		StatusCode int          `json:"status_code" aliases:"status" validate:"omitempty,status_code"`
+		Message    string       `json:"message"`
+		// Page is the filename of a custom response page under `error_pages`.
+		Page string `json:"page"`
+	}
+)
+
+const (
+	geoBlockCacheTTL = time.Minute
+	geoBlockCacheMax = 10000
+)
+
+var ErrGeoBlockMaxMindNotConfigured = gperr.New("MaxMind is not configured, required by country and tz matchers")
+
+var (
+	GeoBlock            = NewMiddleware[geoBlock]()
+	geoBlockOptsDefault = GeoBlockOpts{
+		StatusCode: http.StatusForbidden,
+		Message:    "Access denied in your region",
+	}
+)
+
+// setup implements MiddlewareWithSetup.
+func (gb *geoBlock) setup() {
+	gb.GeoBlockOpts = geoBlockOptsDefault
+	gb.cache = newTTLCache[bool](geoBlockCacheTTL, geoBlockCacheMax)
+}
+
+// finalize implements MiddlewareFinalizerWithError.
+func (gb *geoBlock) finalize() error {
+	// without MaxMind these matchers never match,
+	// which would silently block every client with allow rules only
+	if (gb.Allow.RequiresMaxMind() || gb.Deny.RequiresMaxMind()) && !maxmind.HasInstance() {
+		return ErrGeoBlockMaxMindNotConfigured
+	}
+	switch gb.Default {
+	case acl.ACLAllow:
+		gb.defaultAllow = true
+	case acl.ACLDeny:
+		gb.defaultAllow = false
+	default:
+		gb.defaultAllow = len(gb.Allow) == 0 || len(gb.Deny) > 0
+	}
+	if gb.AllowLocal != nil {
+		gb.allowLocal = *gb.AllowLocal
+	} else {
+		gb.allowLocal = true
+	}
+	return nil
+}
+
+// before implements RequestModifier.
+func (gb *geoBlock) before(w http.ResponseWriter, r *http.Request) (proceed bool) {
+	ipStr, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ipStr = r.RemoteAddr
+	}
+	if gb.allowed(ipStr) {
+		return true
+	}
+
+	if gb.Page != "" {
+		if page, ok := errorpage.GetStaticFile(gb.Page); ok {
+			w.Header().Set(httpheaders.HeaderContentType, "text/html; charset=utf-8")
+			w.WriteHeader(gb.StatusCode)
+			_, _ = w.Write(errorpage.WithRequestID(page, r))
+			return false
+		}
+	}
+	http.Error(w, gb.Message, gb.StatusCode)
+	return false
+}
+
+func (gb *geoBlock) allowed(ipStr string) bool {
+	if allow, ok := gb.cache.Load(ipStr); ok {
+		return allow
+	}
+
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() || (gb.allowLocal && ip.IsPrivate()) {
+		return true
+	}
+
+	info := &maxmind.IPInfo{IP: ip, Str: ipStr}
+	var allow bool
+	switch {
+	case gb.Allow.Match(info):
+		allow = true
+	case gb.Deny.Match(info):
+		allow = false
+	default:
+		allow = gb.defaultAllow
+	}
+	gb.cache.Store(ipStr, allow)
+	return allow
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+
+	. "github.com/yusing/go-proxy/internal/utils/testing"
+)
+
+// httptest.NewRequest uses 192.0.2.1 as the remote address.
+func TestGeoBlock(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   OptionsRaw
+		status int
+	}{
+		{
+			name:   "allow_only",
+			opts:   OptionsRaw{"allow": []string{"ip:192.0.2.1"}},
+			status: http.StatusOK,
+		},
+		{
+			name:   "allow_only_not_matched",
+			opts:   OptionsRaw{"allow": []string{"ip:192.0.2.2"}},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "deny",
+			opts:   OptionsRaw{"deny": []string{"cidr:192.0.2.0/24"}},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "deny_not_matched",
+			opts:   OptionsRaw{"deny": []string{"cidr:198.51.100.0/24"}},
+			status: http.StatusOK,
+		},
+		{
+			name: "allow_over_deny",
+			opts: OptionsRaw{
+				"allow": []string{"ip:192.0.2.1"},
+				"deny":  []string{"cidr:192.0.2.0/24"},
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "default_deny",
+			opts: OptionsRaw{
+				"default": "deny",
+				"deny":    []string{"cidr:198.51.100.0/24"},
+				"status":  http.StatusUnavailableForLegalReasons,
+			},
+			status: http.StatusUnavailableForLegalReasons,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := newMiddlewareTest(GeoBlock, &testArgs{middlewareOpt: tt.opts})
+			ExpectNoError(t, err)
+			ExpectEqual(t, result.ResponseStatus, tt.status)
+		})
+	}
+}
+
+func TestGeoBlockInvalidMatcher(t *testing.T) {
+	_, err := GeoBlock.New(OptionsRaw{"allow": []string{"continent:EU"}})
+	ExpectHasError(t, err)
+}
+
+func TestGeoBlockMaxMindNotConfigured(t *testing.T) {
+	for _, opts := range []OptionsRaw{
+		{"allow": []string{"country:GB"}},
+		{"deny": []string{"tz:Asia/Shanghai"}},
+	} {
+		_, err := GeoBlock.New(opts)
+		ExpectError(t, ErrGeoBlockMaxMindNotConfigured, err)
+	}
+}
@@ -23,6 +23,7 @@ var allMiddlewares = map[string]*Middleware{
 	"modifyresponse": ModifyResponse,
 	"setxforwarded":  SetXForwarded,
 	"hidexforwarded": HideXForwarded,
+	"requestid":      RequestID,
 
 	"errorpage":       CustomErrorPage,
 	"customerrorpage": CustomErrorPage,
@@ -31,7 +32,9 @@ var allMiddlewares = map[string]*Middleware{
 	"cloudflarerealip": CloudflareRealIP,
 
 	"cidrwhitelist": CIDRWhiteList,
+	"geoblock":      GeoBlock,
 	"ratelimit":     RateLimiter,
+	"botfilter":     BotFilter,
 
 	"hcaptcha": HCaptcha,
 }
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/yusing/go-proxy/internal/net/gphttp/httpheaders"
+)
+
+// https://www.w3.org/TR/trace-context/
+
+type (
+	requestID     RequestIDOpts
+	RequestIDOpts struct {
+		// Header is the name of the request ID header
+		Header string `validate:"required"`
+		// TrustIncoming keeps a valid request ID sent by the client (or a proxy in front of GoDoxy)
+		// instead of generating a new one.
+		TrustIncoming bool `json:"trust_incoming"`
+		// TraceContext generates or propagates W3C `traceparent` and `tracestate` headers.
+		TraceContext bool `json:"trace_context"`
+	}
+)
+
+const maxRequestIDLength = 128
+
+var (
+	RequestID            = NewMiddleware[requestID]()
+	requestIDOptsDefault = RequestIDOpts{
+		Header:        httpheaders.HeaderXRequestID,
+		TrustIncoming: true,
+		TraceContext:  true,
+	}
+)
+
+// setup implements MiddlewareWithSetup.
+func (m *requestID) setup() {
+	*m = requestID(requestIDOptsDefault)
+}
+
+// before implements RequestModifier.
+func (m *requestID) before(w http.ResponseWriter, r *http.Request) (proceed bool) {
+	var tp httpheaders.TraceParent
+	var traced bool
+	if m.TraceContext {
+		tp, traced = httpheaders.ParseTraceParent(r.Header.Get(httpheaders.HeaderTraceParent))
+		if traced {
+			tp = tp.NewSpan()
+		} else {
+			tp = httpheaders.NewTraceParent()
+			// tracestate is meaningless without a valid traceparent
+			r.Header.Del(httpheaders.HeaderTraceState)
+		}
+		r.Header.Set(httpheaders.HeaderTraceParent, tp.String())
+	}
+
+	id := r.Header.Get(m.Header)
+	if !m.TrustIncoming || !isValidRequestID(id) {
+		if tp.TraceID != "" {
+			id = tp.TraceID
+		} else {
+			id = httpheaders.NewTraceParent().TraceID
+		}
+		r.Header.Set(m.Header, id)
+	}
+	// the header name is configurable, store the ID for access logs and error pages,
+	// before cannot replace the request so the request is updated in place
+	*r = *httpheaders.WithRequestID(r, id)
+	return true
+}
+
+// modifyResponse implements ResponseModifier.
+func (m *requestID) modifyResponse(resp *http.Response) error {
+	if resp.Request == nil {
+		return nil
+	}
+	if id := resp.Request.Header.Get(m.Header); id != "" {
+		resp.Header.Set(m.Header, id)
+	}
+	return nil
+}
+
+// isValidRequestID returns whether id is a non-empty token of at most maxRequestIDLength
+// characters in [A-Za-z0-9._:-], so it is safe to reflect in headers, logs and error pages.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range []byte(id) {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '.', c == '_', c == ':', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
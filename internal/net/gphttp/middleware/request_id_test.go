@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/yusing/go-proxy/internal/net/gphttp/httpheaders"
+	. "github.com/yusing/go-proxy/internal/utils/testing"
+)
+
+func TestRequestID(t *testing.T) {
+	const (
+		traceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+		traceParent = "00-" + traceID + "-00f067aa0ba902b7-01"
+	)
+
+	t.Run("generate", func(t *testing.T) {
+		result, err := newMiddlewareTest(RequestID, nil)
+		ExpectNoError(t, err)
+		id := result.RequestHeaders.Get(httpheaders.HeaderXRequestID)
+		ExpectEqual(t, len(id), 32)
+		ExpectEqual(t, result.ResponseHeaders.Get(httpheaders.HeaderXRequestID), id)
+
+		tp, ok := httpheaders.ParseTraceParent(result.RequestHeaders.Get(httpheaders.HeaderTraceParent))
+		ExpectTrue(t, ok)
+		ExpectEqual(t, tp.TraceID, id)
+	})
+
+	t.Run("propagate", func(t *testing.T) {
+		result, err := newMiddlewareTest(RequestID, &testArgs{
+			headers: newHeader(
+				httpheaders.HeaderXRequestID, "my-request-id",
+				httpheaders.HeaderTraceParent, traceParent,
+				httpheaders.HeaderTraceState, "vendor=value",
+			),
+		})
+		ExpectNoError(t, err)
+		ExpectEqual(t, result.RequestHeaders.Get(httpheaders.HeaderXRequestID), "my-request-id")
+		ExpectEqual(t, result.ResponseHeaders.Get(httpheaders.HeaderXRequestID), "my-request-id")
+		ExpectEqual(t, result.RequestHeaders.Get(httpheaders.HeaderTraceState), "vendor=value")
+
+		tp, ok := httpheaders.ParseTraceParent(result.RequestHeaders.Get(httpheaders.HeaderTraceParent))
+		ExpectTrue(t, ok)
+		ExpectEqual(t, tp.TraceID, traceID)
+		NotEqual(t, tp.ParentID, "00f067aa0ba902b7")
+	})
+
+	t.Run("invalid_traceparent", func(t *testing.T) {
+		result, err := newMiddlewareTest(RequestID, &testArgs{
+			headers: newHeader(
+				httpheaders.HeaderTraceParent, "00-"+traceID+"-0000000000000000-01",
+				httpheaders.HeaderTraceState, "vendor=value",
+			),
+		})
+		ExpectNoError(t, err)
+		ExpectEqual(t, result.RequestHeaders.Get(httpheaders.HeaderTraceState), "")
+		tp, ok := httpheaders.ParseTraceParent(result.RequestHeaders.Get(httpheaders.HeaderTraceParent))
+		ExpectTrue(t, ok)
+		NotEqual(t, tp.TraceID, traceID)
+	})
+
+	t.Run("invalid_charset", func(t *testing.T) {
+		result, err := newMiddlewareTest(RequestID, &testArgs{
+			headers: newHeader(httpheaders.HeaderXRequestID, "<script>alert(1)</script>"),
+		})
+		ExpectNoError(t, err)
+		ExpectEqual(t, len(result.RequestHeaders.Get(httpheaders.HeaderXRequestID)), 32)
+	})
+
+	t.Run("custom_header", func(t *testing.T) {
+		var id string
+		m, err := RequestID.New(OptionsRaw{"header": "X-Correlation-ID"})
+		ExpectNoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Correlation-ID", "my-request-id")
+		m.ServeHTTP(func(w http.ResponseWriter, r *http.Request) {
+			id = httpheaders.RequestID(r)
+		}, httptest.NewRecorder(), req)
+		ExpectEqual(t, id, "my-request-id")
+	})
+
+	t.Run("untrusted", func(t *testing.T) {
+		result, err := newMiddlewareTest(RequestID, &testArgs{
+			middlewareOpt: OptionsRaw{
+				"trust_incoming": false,
+			},
+			headers: newHeader(httpheaders.HeaderXRequestID, "my-request-id"),
+		})
+		ExpectNoError(t, err)
+		NotEqual(t, result.RequestHeaders.Get(httpheaders.HeaderXRequestID), "my-request-id")
+	})
+}
+
+func newHeader(kv ...string) http.Header {
+	h := make(http.Header, len(kv)/2)
+	for i := 0; i < len(kv); i += 2 {
+		h.Set(kv[i], kv[i+1])
+	}
+	return h
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/yusing/go-proxy/internal/utils"
+	F "github.com/yusing/go-proxy/internal/utils/functional"
+)
+
+// ttlCache is a concurrent cache of results by key, e.g. the client IP,
+// with entries expiring after ttl.
+//
+// Once it grows beyond maxSize, expired entries are swept on store,
+// and it is cleared if it is still full after that,
+// so scanning traffic cannot grow it without bound.
+type ttlCache[V any] struct {
+	m        F.Map[string, ttlCacheEntry[V]]
+	ttl      time.Duration
+	maxSize  int
+	sweeping atomic.Bool
+}
+
+type ttlCacheEntry[V any] struct {
+	value   V
+	expires time.Time
+}
+
+func newTTLCache[V any](ttl time.Duration, maxSize int) *ttlCache[V] {
+	return &ttlCache[V]{
+		m:       F.NewMapOf[string, ttlCacheEntry[V]](),
+		ttl:     ttl,
+		maxSize: maxSize,
+	}
+}
+
+// Load returns the value of key if it exists and is not expired.
+func (c *ttlCache[V]) Load(key string) (v V, ok bool) {
+	entry, ok := c.m.Load(key)
+	if !ok || !utils.TimeNow().Before(entry.expires) {
+		return v, false
+	}
+	return entry.value, true
+}
+
+func (c *ttlCache[V]) Store(key string, v V) {
+	c.m.Store(key, ttlCacheEntry[V]{value: v, expires: utils.TimeNow().Add(c.ttl)})
+	if c.m.Size() > c.maxSize && c.sweeping.CompareAndSwap(false, true) {
+		defer c.sweeping.Store(false)
+		c.sweep()
+	}
+}
+
+func (c *ttlCache[V]) sweep() {
+	now := utils.TimeNow()
+	c.m.Range(func(k string, entry ttlCacheEntry[V]) bool {
+		if !now.Before(entry.expires) {
+			c.m.Delete(k)
+		}
+		return true
+	})
+	if c.m.Size() > c.maxSize {
+		c.m.Clear()
+	}
+}
+
+func (c *ttlCache[V]) Size() int {
+	return c.m.Size()
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/yusing/go-proxy/internal/utils/testing"
+)
+
+func TestTTLCache(t *testing.T) {
+	c := newTTLCache[bool](time.Hour, 4)
+	c.Store("1.2.3.4", true)
+	v, ok := c.Load("1.2.3.4")
+	ExpectTrue(t, ok)
+	ExpectTrue(t, v)
+	_, ok = c.Load("5.6.7.8")
+	ExpectFalse(t, ok)
+
+	for i := range 100 {
+		c.Store(strconv.Itoa(i), true)
+		ExpectTrue(t, c.Size() <= 4)
+	}
+}
+
+func TestTTLCacheExpired(t *testing.T) {
+	c := newTTLCache[bool](-time.Second, 4)
+	c.Store("1.2.3.4", true)
+	_, ok := c.Load("1.2.3.4")
+	ExpectFalse(t, ok)
+
+	for i := range 4 {
+		c.Store(strconv.Itoa(i), true)
+	}
+	// expired entries are swept
+	ExpectEqual(t, c.Size(), 0)
+}
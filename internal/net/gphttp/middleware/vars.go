@@ -105,6 +105,11 @@ var staticRespVarSubsMap = map[string]respVarGetter{
 	VarRespStatusCode:  func(resp *http.Response) string { return strconv.Itoa(resp.StatusCode) },
 }
 
+// ReplaceRequestVars replaces the request variables in s, e.g. `$req_host` and `$arg(name)`.
+func ReplaceRequestVars(req *http.Request, s string) string {
+	return varReplace(req, nil, s)
+}
+
 func varReplace(req *http.Request, resp *http.Response, s string) string {
 	if req != nil {
 		// Replace query parameters
@@ -27,6 +27,7 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/yusing/go-proxy/internal/logging/accesslog"
+	gphttp "github.com/yusing/go-proxy/internal/net/gphttp"
 	"github.com/yusing/go-proxy/internal/net/gphttp/httpheaders"
 	"github.com/yusing/go-proxy/internal/net/types"
 	U "github.com/yusing/go-proxy/internal/utils"
@@ -92,6 +93,9 @@ type ReverseProxy struct {
 
 	HandlerFunc http.HandlerFunc
 
+	// HostHeader overrides the Host header of requests to the target, if not empty.
+	HostHeader string
+
 	TargetName string
 	TargetURL  *types.URL
 }
@@ -263,6 +267,9 @@ func (p *ReverseProxy) handler(rw http.ResponseWriter, req *http.Request) {
 	}
 
 	p.rewriteRequestURL(outreq)
+	if p.HostHeader != "" {
+		outreq.Host = p.HostHeader
+	}
 	outreq.Close = false
 
 	reqUpType := httpheaders.UpgradeType(outreq.Header)
@@ -358,19 +365,36 @@ func (p *ReverseProxy) handler(rw http.ResponseWriter, req *http.Request) {
 	roundTripMutex.Lock()
 	roundTripDone = true
 	roundTripMutex.Unlock()
-	if err != nil {
+	isGRPC := httpheaders.IsGRPC(req.Header)
+	switch {
+	case err != nil && isGRPC:
+		p.errorHandler(rw, outreq, err, false)
+		code := gphttp.GRPCStatusUnavailable
+		if errors.Is(err, context.DeadlineExceeded) {
+			code = gphttp.GRPCStatusDeadlineExceeded
+		}
+		res = grpcErrorResponse(req, code, "origin server is not reachable")
+	case err != nil:
 		p.errorHandler(rw, outreq, err, false)
+		code, msg := http.StatusBadGateway, "Origin server is not reachable."
+		if errors.Is(err, context.DeadlineExceeded) {
+			code, msg = http.StatusGatewayTimeout, "Origin server did not respond in time."
+		}
 		res = &http.Response{
-			Status:     http.StatusText(http.StatusBadGateway),
-			StatusCode: http.StatusBadGateway,
+			Status:     http.StatusText(code),
+			StatusCode: code,
 			Proto:      req.Proto,
 			ProtoMajor: req.ProtoMajor,
 			ProtoMinor: req.ProtoMinor,
 			Header:     http.Header{},
-			Body:       io.NopCloser(bytes.NewReader([]byte("Origin server is not reachable."))),
+			Body:       io.NopCloser(bytes.NewReader([]byte(msg))),
 			Request:    req,
 			TLS:        req.TLS,
 		}
+	case isGRPC && res.StatusCode != http.StatusOK && !httpheaders.IsGRPC(res.Header):
+		// gRPC clients ignore the HTTP status, map it to a gRPC status
+		res.Body.Close()
+		res = grpcErrorResponse(req, gphttp.GRPCStatusFromHTTP(res.StatusCode), "upstream responded with "+res.Status)
 	}
 
 	if p.AccessLogger != nil {
@@ -439,6 +463,21 @@ func (p *ReverseProxy) handler(rw http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// grpcErrorResponse returns a trailers-only gRPC response with the status code and message.
+func grpcErrorResponse(req *http.Request, code int, msg string) *http.Response {
+	return &http.Response{
+		Status:     http.StatusText(http.StatusOK),
+		StatusCode: http.StatusOK,
+		Proto:      req.Proto,
+		ProtoMajor: req.ProtoMajor,
+		ProtoMinor: req.ProtoMinor,
+		Header:     gphttp.GRPCErrorHeader(code, msg),
+		Body:       http.NoBody,
+		Request:    req,
+		TLS:        req.TLS,
+	}
+}
+
 // reference: https://github.com/traefik/traefik/blob/master/pkg/proxy/httputil/proxy.go
 // https://tools.ietf.org/html/rfc6455#page-20
 func cleanWebsocketHeaders(req *http.Request) {
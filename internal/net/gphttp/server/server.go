@@ -14,6 +14,7 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/yusing/go-proxy/internal/acl"
 	"github.com/yusing/go-proxy/internal/common"
+	"github.com/yusing/go-proxy/internal/net/proxyproto"
 	"github.com/yusing/go-proxy/internal/task"
 )
 
@@ -29,6 +30,9 @@ type Server struct {
 	startTime    time.Time
 	acl          *acl.Config
 
+	tlsPassthrough TLSPassthroughFunc
+	proxyProtocol  *proxyproto.Config
+
 	l zerolog.Logger
 }
 
@@ -39,6 +43,12 @@ type Options struct {
 	CertProvider CertProvider
 	Handler      http.Handler
 	ACL          *acl.Config
+	// TLSPassthrough routes TLS connections by SNI before TLS termination on the HTTPS listener.
+	TLSPassthrough TLSPassthroughFunc
+	// ProxyProtocol accepts PROXY protocol headers on the HTTP and HTTPS listeners.
+	ProxyProtocol *proxyproto.Config
+	// H2C accepts HTTP/2 with prior knowledge on the HTTP listener, e.g. from gRPC clients without TLS.
+	H2C bool
 }
 
 type httpServer interface {
@@ -68,6 +78,11 @@ func NewServer(opt Options) (s *Server) {
 			Addr:    opt.HTTPAddr,
 			Handler: opt.Handler,
 		}
+		if opt.H2C {
+			httpSer.Protocols = new(http.Protocols)
+			httpSer.Protocols.SetHTTP1(true)
+			httpSer.Protocols.SetUnencryptedHTTP2(true)
+		}
 	}
 	if certAvailable && opt.HTTPSAddr != "" {
 		httpsSer = &http.Server{
@@ -86,6 +101,9 @@ func NewServer(opt Options) (s *Server) {
 		https:        httpsSer,
 		l:            logger,
 		acl:          opt.ACL,
+
+		tlsPassthrough: opt.TLSPassthrough,
+		proxyProtocol:  opt.ProxyProtocol,
 	}
 }
 
@@ -113,11 +131,34 @@ func (s *Server) Start(parent task.Parent) {
 		s.https.Handler = advertiseHTTP3(s.https.Handler, h3)
 	}
 
-	Start(subtask, s.http, s.acl, &s.l)
-	Start(subtask, s.https, s.acl, &s.l)
+	// ACL of the TCP listeners is checked by wrapTCP
+	start(subtask, s.http, nil, &s.l, s.wrapTCP(false))
+	start(subtask, s.https, nil, &s.l, s.wrapTCP(s.tlsPassthrough != nil))
+}
+
+// wrapTCP returns the wrapper of the TCP listeners for PROXY protocol, ACL and TLS passthrough.
+//
+// PROXY protocol goes first, so ACL sees the address of the real client,
+// then ACL, so it is checked once for both passthrough and terminated connections.
+func (s *Server) wrapTCP(tlsPassthrough bool) func(net.Listener) net.Listener {
+	return func(l net.Listener) net.Listener {
+		l = s.proxyProtocol.WrapListener(l, &s.l)
+		if s.acl != nil {
+			l = s.acl.WrapTCP(l)
+		}
+		if tlsPassthrough {
+			l = newTLSPassthroughListener(l, s.tlsPassthrough, &s.l)
+		}
+		return l
+	}
 }
 
 func Start[Server httpServer](parent task.Parent, srv Server, acl *acl.Config, logger *zerolog.Logger) (port int) {
+	return start(parent, srv, acl, logger, nil)
+}
+
+// start starts the server, wrapTCP wraps the TCP listener before TLS termination if not nil.
+func start[Server httpServer](parent task.Parent, srv Server, acl *acl.Config, logger *zerolog.Logger, wrapTCP func(net.Listener) net.Listener) (port int) {
 	if srv == nil {
 		return
 	}
@@ -141,6 +182,9 @@ func Start[Server httpServer](parent task.Parent, srv Server, acl *acl.Config, l
 			return
 		}
 		port = l.Addr().(*net.TCPAddr).Port
+		if wrapTCP != nil {
+			l = wrapTCP(l)
+		}
 		if srv.TLSConfig != nil {
 			l = tls.NewListener(l, srv.TLSConfig)
 		}
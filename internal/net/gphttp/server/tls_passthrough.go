@@ -0,0 +1,155 @@
+package server
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type (
+	// TLSPassthroughFunc returns the handler of raw TLS connections for the server name,
+	// or nil if the connection should be handled by the HTTPS server.
+	TLSPassthroughFunc func(serverName string) (handle func(conn net.Conn))
+
+	// tlsPassthroughListener peeks at the ClientHello of accepted connections,
+	// hands them to the passthrough handler on a matching SNI,
+	// and returns the rest from Accept.
+	tlsPassthroughListener struct {
+		net.Listener
+
+		passthrough TLSPassthroughFunc
+
+		conns     chan net.Conn
+		done      chan struct{}
+		closeOnce sync.Once
+		err       error
+
+		l *zerolog.Logger
+	}
+
+	// peekedConn replays the peeked ClientHello before reading from the connection.
+	peekedConn struct {
+		net.Conn
+		r io.Reader
+	}
+
+	// readOnlyConn fails writes, so the TLS handshake stops right after reading the ClientHello.
+	readOnlyConn struct {
+		r io.Reader
+	}
+)
+
+const clientHelloTimeout = 5 * time.Second
+
+var errReadOnlyConn = errors.New("read only connection")
+
+func newTLSPassthroughListener(l net.Listener, passthrough TLSPassthroughFunc, logger *zerolog.Logger) *tlsPassthroughListener {
+	pl := &tlsPassthroughListener{
+		Listener:    l,
+		passthrough: passthrough,
+		conns:       make(chan net.Conn),
+		done:        make(chan struct{}),
+		l:           logger,
+	}
+	go pl.acceptLoop()
+	return pl
+}
+
+func (pl *tlsPassthroughListener) acceptLoop() {
+	for {
+		conn, err := pl.Listener.Accept()
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			pl.err = err
+			pl.closeOnce.Do(func() { close(pl.done) })
+			return
+		}
+		go pl.handle(conn)
+	}
+}
+
+func (pl *tlsPassthroughListener) handle(conn net.Conn) {
+	_ = conn.SetReadDeadline(time.Now().Add(clientHelloTimeout))
+	hello, peeked, err := peekClientHello(conn)
+	_ = conn.SetReadDeadline(time.Time{})
+
+	conn = &peekedConn{Conn: conn, r: peeked}
+	// not a valid ClientHello, let the HTTPS server deal with it
+	if err == nil && hello.ServerName != "" {
+		if handle := pl.passthrough(hello.ServerName); handle != nil {
+			pl.l.Debug().Str("sni", hello.ServerName).Str("remote", conn.RemoteAddr().String()).Msg("tls passthrough")
+			handle(conn)
+			return
+		}
+	}
+
+	select {
+	case pl.conns <- conn:
+	case <-pl.done:
+		conn.Close()
+	}
+}
+
+// Accept implements net.Listener.
+func (pl *tlsPassthroughListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-pl.conns:
+		return conn, nil
+	case <-pl.done:
+		if pl.err != nil {
+			return nil, pl.err
+		}
+		return nil, net.ErrClosed
+	}
+}
+
+// Close implements net.Listener.
+func (pl *tlsPassthroughListener) Close() error {
+	pl.closeOnce.Do(func() { close(pl.done) })
+	return pl.Listener.Close()
+}
+
+// peekClientHello reads the ClientHello from r,
+// and returns it with a reader that replays the consumed bytes.
+func peekClientHello(r io.Reader) (*tls.ClientHelloInfo, io.Reader, error) {
+	var peeked bytes.Buffer
+	hello, err := readClientHello(io.TeeReader(r, &peeked))
+	return hello, io.MultiReader(&peeked, r), err
+}
+
+func readClientHello(r io.Reader) (*tls.ClientHelloInfo, error) {
+	var hello *tls.ClientHelloInfo
+	err := tls.Server(readOnlyConn{r: r}, &tls.Config{
+		GetConfigForClient: func(info *tls.ClientHelloInfo) (*tls.Config, error) {
+			hello = new(tls.ClientHelloInfo)
+			*hello = *info
+			return nil, nil
+		},
+	}).Handshake()
+	if hello == nil {
+		return nil, err
+	}
+	return hello, nil
+}
+
+func (c *peekedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+func (c readOnlyConn) Read(b []byte) (int, error)         { return c.r.Read(b) }
+func (c readOnlyConn) Write(b []byte) (int, error)        { return 0, errReadOnlyConn }
+func (c readOnlyConn) Close() error                       { return nil }
+func (c readOnlyConn) LocalAddr() net.Addr                { return nil }
+func (c readOnlyConn) RemoteAddr() net.Addr               { return nil }
+func (c readOnlyConn) SetDeadline(t time.Time) error      { return nil }
+func (c readOnlyConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c readOnlyConn) SetWriteDeadline(t time.Time) error { return nil }
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"testing"
+
+	expect "github.com/yusing/go-proxy/internal/utils/testing"
+)
+
+func TestPeekClientHello(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	go func() {
+		defer client.Close()
+		_ = tls.Client(client, &tls.Config{ServerName: "app.example.com"}).Handshake()
+	}()
+
+	hello, peeked, err := peekClientHello(server)
+	expect.NoError(t, err)
+	expect.Equal(t, hello.ServerName, "app.example.com")
+
+	// the peeked bytes are replayed, so the connection is still a valid TLS stream
+	replayed, err := readClientHello(peeked)
+	expect.NoError(t, err)
+	expect.Equal(t, replayed.ServerName, "app.example.com")
+}
+
+func TestPeekClientHelloNotTLS(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	go func() {
+		defer client.Close()
+		_, _ = client.Write([]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+	}()
+
+	_, peeked, err := peekClientHello(server)
+	expect.HasError(t, err)
+
+	b := make([]byte, 3)
+	_, err = io.ReadFull(peeked, b)
+	expect.NoError(t, err)
+	expect.Equal(t, string(b), "GET")
+}
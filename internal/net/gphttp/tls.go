@@ -0,0 +1,101 @@
+package gphttp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// UpstreamTLSOptions configures TLS connections to upstreams.
+type UpstreamTLSOptions struct {
+	SkipVerify bool
+	// ServerName overrides the server name to verify the upstream certificate against and to send in SNI.
+	ServerName string
+	// CA is the path of PEM encoded CA certificates to verify the upstream certificate with,
+	// instead of the system CA certificates.
+	CA string
+	// Cert and Key are the paths of the PEM encoded client certificate and key.
+	Cert string
+	Key  string
+	// Protocols is the allowed TLS versions, e.g. `tlsv1.2` and `tlsv1.3`.
+	Protocols []string
+}
+
+var tlsVersions = map[string]uint16{
+	"tlsv1":   tls.VersionTLS10,
+	"tlsv1.0": tls.VersionTLS10,
+	"tlsv1.1": tls.VersionTLS11,
+	"tlsv1.2": tls.VersionTLS12,
+	"tlsv1.3": tls.VersionTLS13,
+}
+
+var ErrInvalidTLSVersion = errors.New("invalid TLS version")
+
+// IsZero returns whether the options are all default,
+// i.e. upstreams are verified with the system CA certificates.
+func (opts *UpstreamTLSOptions) IsZero() bool {
+	return !opts.SkipVerify && opts.ServerName == "" && opts.CA == "" &&
+		opts.Cert == "" && opts.Key == "" && len(opts.Protocols) == 0
+}
+
+// Validate validates the options without reading the files.
+func (opts *UpstreamTLSOptions) Validate() error {
+	if (opts.Cert == "") != (opts.Key == "") {
+		return errors.New("ssl_cert and ssl_key must be set together")
+	}
+	_, _, err := opts.versions()
+	return err
+}
+
+// TLSConfig returns the TLS config of the options, reading the certificates from files.
+func (opts *UpstreamTLSOptions) TLSConfig() (*tls.Config, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		InsecureSkipVerify: opts.SkipVerify, //nolint:gosec
+		ServerName:         opts.ServerName,
+	}
+	cfg.MinVersion, cfg.MaxVersion, _ = opts.versions()
+
+	if opts.CA != "" {
+		pem, err := os.ReadFile(opts.CA)
+		if err != nil {
+			return nil, fmt.Errorf("read CA certificates: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no CA certificate found in %s", opts.CA)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if opts.Cert != "" {
+		cert, err := tls.LoadX509KeyPair(opts.Cert, opts.Key)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// versions returns the minimum and maximum TLS versions of the protocols,
+// zero if not set.
+func (opts *UpstreamTLSOptions) versions() (minVersion, maxVersion uint16, err error) {
+	for _, p := range opts.Protocols {
+		v, ok := tlsVersions[strings.ToLower(strings.TrimSpace(p))]
+		if !ok {
+			return 0, 0, fmt.Errorf("%w: %s", ErrInvalidTLSVersion, p)
+		}
+		if minVersion == 0 || v < minVersion {
+			minVersion = v
+		}
+		maxVersion = max(maxVersion, v)
+	}
+	return minVersion, maxVersion, nil
+}
@@ -0,0 +1,148 @@
+package gphttp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/yusing/go-proxy/internal/utils/testing"
+)
+
+func writePEM(t *testing.T, path, typ string, der []byte) {
+	t.Helper()
+	ExpectNoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600))
+}
+
+// newTestCA writes a CA certificate, a server certificate for `upstream.internal`
+// and a client certificate signed by the CA to dir.
+func newTestCA(t *testing.T, dir string) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ExpectNoError(t, err)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	ExpectNoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	ExpectNoError(t, err)
+	writePEM(t, filepath.Join(dir, "ca.pem"), "CERTIFICATE", caDER)
+
+	issue := func(name string, serial int64, usage x509.ExtKeyUsage, dnsNames ...string) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		ExpectNoError(t, err)
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			DNSNames:     dnsNames,
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+		ExpectNoError(t, err)
+		writePEM(t, filepath.Join(dir, name+".pem"), "CERTIFICATE", der)
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		ExpectNoError(t, err)
+		writePEM(t, filepath.Join(dir, name+".key"), "EC PRIVATE KEY", keyDER)
+	}
+	issue("server", 2, x509.ExtKeyUsageServerAuth, "upstream.internal")
+	issue("client", 3, x509.ExtKeyUsageClientAuth)
+}
+
+func TestUpstreamTLSOptionsValidate(t *testing.T) {
+	ExpectTrue(t, (&UpstreamTLSOptions{}).IsZero())
+	ExpectNoError(t, (&UpstreamTLSOptions{Protocols: []string{"TLSv1.2", "tlsv1.3"}}).Validate())
+	ExpectError(t, ErrInvalidTLSVersion, (&UpstreamTLSOptions{Protocols: []string{"sslv3"}}).Validate())
+	ExpectHasError(t, (&UpstreamTLSOptions{Cert: "client.pem"}).Validate())
+
+	cfg, err := (&UpstreamTLSOptions{ServerName: "internal", Protocols: []string{"tlsv1.3", "tlsv1.2"}}).TLSConfig()
+	ExpectNoError(t, err)
+	ExpectEqual(t, cfg.ServerName, "internal")
+	ExpectEqual(t, cfg.MinVersion, tls.VersionTLS12)
+	ExpectEqual(t, cfg.MaxVersion, tls.VersionTLS13)
+}
+
+func TestUpstreamTLSOptionsMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	newTestCA(t, dir)
+
+	serverCert, err := tls.LoadX509KeyPair(filepath.Join(dir, "server.pem"), filepath.Join(dir, "server.key"))
+	ExpectNoError(t, err)
+	clientCAs := x509.NewCertPool()
+	caPEM, err := os.ReadFile(filepath.Join(dir, "ca.pem"))
+	ExpectNoError(t, err)
+	clientCAs.AppendCertsFromPEM(caPEM)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	get := func(opts *UpstreamTLSOptions) (string, error) {
+		tlsConfig, err := opts.TLSConfig()
+		ExpectNoError(t, err)
+		client := &http.Client{Transport: NewTransportWithTLSConfig(tlsConfig)}
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	opts := &UpstreamTLSOptions{
+		ServerName: "upstream.internal",
+		CA:         filepath.Join(dir, "ca.pem"),
+		Cert:       filepath.Join(dir, "client.pem"),
+		Key:        filepath.Join(dir, "client.key"),
+	}
+	body, err := get(opts)
+	ExpectNoError(t, err)
+	ExpectEqual(t, body, "client")
+
+	t.Run("without server name", func(t *testing.T) {
+		opts := *opts
+		opts.ServerName = ""
+		_, err := get(&opts)
+		ExpectHasError(t, err)
+	})
+
+	t.Run("without CA", func(t *testing.T) {
+		opts := *opts
+		opts.CA = ""
+		_, err := get(&opts)
+		ExpectHasError(t, err)
+	})
+
+	t.Run("invalid CA", func(t *testing.T) {
+		_, err := (&UpstreamTLSOptions{CA: filepath.Join(dir, "client.key")}).TLSConfig()
+		ExpectHasError(t, err)
+	})
+}
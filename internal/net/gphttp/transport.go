@@ -2,6 +2,8 @@ package gphttp
 
 import (
 	"crypto/tls"
+	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"time"
@@ -32,3 +34,105 @@ func NewTransportWithTLSConfig(tlsConfig *tls.Config) *http.Transport {
 	tr.TLSClientConfig = tlsConfig
 	return tr
 }
+
+// NewH2CTransport returns a transport that speaks HTTP/2 over cleartext (h2c) with prior knowledge.
+func NewH2CTransport() *http.Transport {
+	tr := NewTransport()
+	tr.Protocols = new(http.Protocols)
+	tr.Protocols.SetUnencryptedHTTP2(true)
+	return tr
+}
+
+// NewHTTP2Transport returns a transport that only speaks HTTP/2 over TLS.
+func NewHTTP2Transport() *http.Transport {
+	tr := NewTransport()
+	tr.Protocols = new(http.Protocols)
+	tr.Protocols.SetHTTP2(true)
+	return tr
+}
+
+// TransportOptions tunes connections to upstreams, zero values keep the defaults.
+type TransportOptions struct {
+	DialTimeout time.Duration
+	// KeepAlive is the interval of TCP keep-alive probes, negative to disable.
+	KeepAlive time.Duration
+	// DisableKeepAlives disables reusing connections for multiple requests.
+	DisableKeepAlives   bool
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost limits the number of connections including in-use ones,
+	// requests wait for a connection when the limit is reached.
+	MaxConnsPerHost int
+	IdleConnTimeout time.Duration
+	// HTTPVersion forces the HTTP version of requests to upstreams, `1.1` or `2`.
+	//
+	// `2` speaks HTTP/2 over cleartext (h2c) with prior knowledge to http upstreams.
+	HTTPVersion string
+}
+
+const (
+	HTTPVersion1 = "1.1"
+	HTTPVersion2 = "2"
+)
+
+var ErrInvalidHTTPVersion = errors.New("invalid HTTP version, must be 1.1 or 2")
+
+func (opts *TransportOptions) IsZero() bool {
+	return *opts == TransportOptions{}
+}
+
+func (opts *TransportOptions) Validate() error {
+	switch opts.HTTPVersion {
+	case "", HTTPVersion1, HTTPVersion2:
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidHTTPVersion, opts.HTTPVersion)
+	}
+	if opts.MaxIdleConns < 0 || opts.MaxIdleConnsPerHost < 0 || opts.MaxConnsPerHost < 0 {
+		return errors.New("connection limits must not be negative")
+	}
+	return nil
+}
+
+// Dialer returns the dialer with the dial timeout and keep-alive of the options.
+func (opts *TransportOptions) Dialer() *net.Dialer {
+	dialer := DefaultDialer
+	if opts.DialTimeout > 0 {
+		dialer.Timeout = opts.DialTimeout
+	}
+	if opts.KeepAlive != 0 {
+		dialer.KeepAlive = opts.KeepAlive
+	}
+	return &dialer
+}
+
+// Apply applies the options to the transport.
+func (opts *TransportOptions) Apply(tr *http.Transport) {
+	if opts.DialTimeout > 0 || opts.KeepAlive != 0 {
+		tr.DialContext = opts.Dialer().DialContext
+	}
+	if opts.DisableKeepAlives {
+		tr.DisableKeepAlives = true
+	}
+	if opts.MaxIdleConns > 0 {
+		tr.MaxIdleConns = opts.MaxIdleConns
+	}
+	if opts.MaxIdleConnsPerHost > 0 {
+		tr.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	}
+	if opts.MaxConnsPerHost > 0 {
+		tr.MaxConnsPerHost = opts.MaxConnsPerHost
+	}
+	if opts.IdleConnTimeout > 0 {
+		tr.IdleConnTimeout = opts.IdleConnTimeout
+	}
+	switch opts.HTTPVersion {
+	case HTTPVersion1:
+		tr.Protocols = new(http.Protocols)
+		tr.Protocols.SetHTTP1(true)
+		tr.ForceAttemptHTTP2 = false
+	case HTTPVersion2:
+		tr.Protocols = new(http.Protocols)
+		tr.Protocols.SetHTTP2(true)
+		tr.Protocols.SetUnencryptedHTTP2(true)
+	}
+}
@@ -0,0 +1,68 @@
+package gphttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/yusing/go-proxy/internal/utils/testing"
+)
+
+func TestTransportOptionsValidate(t *testing.T) {
+	ExpectTrue(t, (&TransportOptions{}).IsZero())
+	ExpectNoError(t, (&TransportOptions{HTTPVersion: HTTPVersion1}).Validate())
+	ExpectNoError(t, (&TransportOptions{HTTPVersion: HTTPVersion2}).Validate())
+	ExpectError(t, ErrInvalidHTTPVersion, (&TransportOptions{HTTPVersion: "3"}).Validate())
+	ExpectHasError(t, (&TransportOptions{MaxConnsPerHost: -1}).Validate())
+}
+
+func TestTransportOptionsApply(t *testing.T) {
+	tr := NewTransport()
+	(&TransportOptions{}).Apply(tr)
+	ExpectEqual(t, tr.MaxIdleConnsPerHost, 100)
+	ExpectEqual(t, tr.IdleConnTimeout, 90*time.Second)
+
+	tr = NewTransport()
+	(&TransportOptions{
+		DialTimeout:         time.Second,
+		DisableKeepAlives:   true,
+		MaxIdleConns:        10,
+		MaxIdleConnsPerHost: 5,
+		MaxConnsPerHost:     20,
+		IdleConnTimeout:     time.Minute,
+	}).Apply(tr)
+	ExpectTrue(t, tr.DisableKeepAlives)
+	ExpectEqual(t, tr.MaxIdleConns, 10)
+	ExpectEqual(t, tr.MaxIdleConnsPerHost, 5)
+	ExpectEqual(t, tr.MaxConnsPerHost, 20)
+	ExpectEqual(t, tr.IdleConnTimeout, time.Minute)
+	ExpectEqual(t, (&TransportOptions{DialTimeout: time.Second}).Dialer().Timeout, time.Second)
+}
+
+func TestTransportOptionsHTTPVersion(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		version string
+		want    string
+	}{
+		{HTTPVersion1, "HTTP/1.1"},
+		{HTTPVersion2, "HTTP/2.0"},
+	} {
+		t.Run(tt.version, func(t *testing.T) {
+			tr := NewTransport()
+			tr.TLSClientConfig = srv.Client().Transport.(*http.Transport).TLSClientConfig
+			(&TransportOptions{HTTPVersion: tt.version}).Apply(tr)
+			resp, err := (&http.Client{Transport: tr}).Get(srv.URL)
+			ExpectNoError(t, err)
+			defer resp.Body.Close()
+			ExpectEqual(t, resp.Proto, tt.want)
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package proxyproto
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+type (
+	DialFunc      func(ctx context.Context, network, addr string) (net.Conn, error)
+	clientAddrKey struct{}
+)
+
+// WithClientAddr returns a shallow copy of r with the client address in its context,
+// for dialers returned by Dialer.
+func WithClientAddr(r *http.Request) *http.Request {
+	addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr)
+	if err != nil {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), clientAddrKey{}, addr))
+}
+
+// Dialer returns a dial function that sends a header of the version after dialing,
+// with the client address set by WithClientAddr and the local address of the server that accepted the request.
+//
+// A LOCAL (v2) or UNKNOWN (v1) header is sent if the client address is unknown.
+func Dialer(version Version, dial DialFunc) DialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		src, _ := ctx.Value(clientAddrKey{}).(net.Addr)
+		dst, _ := ctx.Value(http.LocalAddrContextKey).(net.Addr)
+		if err := WriteHeader(conn, version, src, dst); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+}
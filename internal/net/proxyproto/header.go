@@ -0,0 +1,249 @@
+// Package proxyproto implements the PROXY protocol v1 and v2.
+//
+// https://www.haproxy.org/download/2.9/doc/proxy-protocol.txt
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/yusing/go-proxy/internal/gperr"
+)
+
+type (
+	Version int
+
+	// Header is a PROXY protocol header.
+	//
+	// Src and Dst are nil for LOCAL (v2) and UNKNOWN (v1) headers,
+	// e.g. health checks from the load balancer itself.
+	Header struct {
+		Version Version
+		Src     *net.TCPAddr
+		Dst     *net.TCPAddr
+	}
+)
+
+const (
+	V1 Version = 1
+	V2 Version = 2
+)
+
+const (
+	v1MaxLength = 107
+
+	v2HeaderLength = 16
+	v2CmdLocal     = 0x20
+	v2CmdProxy     = 0x21
+	v2FamUnspec    = 0x00
+	v2FamTCP4      = 0x11
+	v2FamTCP6      = 0x21
+	v2AddrLenTCP4  = 12
+	v2AddrLenTCP6  = 36
+)
+
+var (
+	v1Signature = []byte("PROXY ")
+	v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+var (
+	ErrNoHeader      = errors.New("no PROXY protocol header")
+	ErrInvalidHeader = gperr.New("invalid PROXY protocol header")
+)
+
+// ReadHeader reads a v1 or v2 header from r.
+//
+// It returns ErrNoHeader without consuming any bytes if r does not start with a header.
+func ReadHeader(r *bufio.Reader) (*Header, error) {
+	b, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	switch b[0] {
+	case v1Signature[0]:
+		b, err := r.Peek(len(v1Signature))
+		if err != nil || !bytes.Equal(b, v1Signature) {
+			return nil, ErrNoHeader
+		}
+		return readV1(r)
+	case v2Signature[0]:
+		b, err := r.Peek(len(v2Signature))
+		if err != nil || !bytes.Equal(b, v2Signature) {
+			return nil, ErrNoHeader
+		}
+		return readV2(r)
+	}
+	return nil, ErrNoHeader
+}
+
+func readV1(r *bufio.Reader) (*Header, error) {
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		if errors.Is(err, bufio.ErrBufferFull) {
+			return nil, ErrInvalidHeader.Withf("v1 header too long")
+		}
+		return nil, err
+	}
+	if len(line) > v1MaxLength || !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, ErrInvalidHeader.Withf("v1 header too long or not terminated by CRLF")
+	}
+	fields := strings.Fields(string(line[:len(line)-2]))
+	// PROXY <proto> <src ip> <dst ip> <src port> <dst port>
+	h := &Header{Version: V1}
+	if len(fields) < 2 {
+		return nil, ErrInvalidHeader.Subject(string(line))
+	}
+	switch fields[1] {
+	case "UNKNOWN":
+		return h, nil
+	case "TCP4", "TCP6":
+	default:
+		return nil, ErrInvalidHeader.Withf("unsupported protocol %q", fields[1])
+	}
+	if len(fields) != 6 {
+		return nil, ErrInvalidHeader.Subject(string(line))
+	}
+	h.Src, err = parseV1Addr(fields[2], fields[4])
+	if err != nil {
+		return nil, err
+	}
+	h.Dst, err = parseV1Addr(fields[3], fields[5])
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func parseV1Addr(ip, port string) (*net.TCPAddr, error) {
+	addr := &net.TCPAddr{IP: net.ParseIP(ip)}
+	if addr.IP == nil {
+		return nil, ErrInvalidHeader.Withf("invalid ip %q", ip)
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, ErrInvalidHeader.Withf("invalid port %q", port)
+	}
+	addr.Port = int(p)
+	return addr, nil
+}
+
+func readV2(r *bufio.Reader) (*Header, error) {
+	var hdr [v2HeaderLength]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	cmd, fam := hdr[12], hdr[13]
+	length := int(binary.BigEndian.Uint16(hdr[14:]))
+
+	h := &Header{Version: V2}
+	switch cmd {
+	case v2CmdLocal:
+		// addresses, if any, are ignored
+	case v2CmdProxy:
+		var ipLen int
+		switch fam {
+		case v2FamTCP4:
+			ipLen = net.IPv4len
+		case v2FamTCP6:
+			ipLen = net.IPv6len
+		}
+		if ipLen == 0 {
+			// unsupported address family, the receiver must accept the connection
+			// and use the real connection endpoints.
+			break
+		}
+		if length < 2*ipLen+4 {
+			return nil, ErrInvalidHeader.Withf("v2 address length %d too short", length)
+		}
+		addrs := make([]byte, 2*ipLen+4)
+		if _, err := io.ReadFull(r, addrs); err != nil {
+			return nil, err
+		}
+		length -= len(addrs)
+		h.Src = &net.TCPAddr{
+			IP:   net.IP(addrs[:ipLen]),
+			Port: int(binary.BigEndian.Uint16(addrs[2*ipLen:])),
+		}
+		h.Dst = &net.TCPAddr{
+			IP:   net.IP(addrs[ipLen : 2*ipLen]),
+			Port: int(binary.BigEndian.Uint16(addrs[2*ipLen+2:])),
+		}
+	default:
+		return nil, ErrInvalidHeader.Withf("unsupported v2 version/command 0x%x", cmd)
+	}
+	// skip TLVs
+	if _, err := r.Discard(length); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Format returns the header in the wire format.
+//
+// If Src or Dst is nil, or the address families mismatch,
+// an UNKNOWN (v1) or LOCAL (v2) header is returned.
+func (h *Header) Format() []byte {
+	src, dst := h.addrs()
+	if h.Version == V1 {
+		if src == nil {
+			return []byte("PROXY UNKNOWN\r\n")
+		}
+		proto := "TCP4"
+		if src.IP.To4() == nil {
+			proto = "TCP6"
+		}
+		return fmt.Appendf(nil, "PROXY %s %s %s %d %d\r\n", proto, src.IP, dst.IP, src.Port, dst.Port)
+	}
+
+	b := make([]byte, v2HeaderLength, v2HeaderLength+v2AddrLenTCP6)
+	copy(b, v2Signature)
+	if src == nil {
+		b[12], b[13] = v2CmdLocal, v2FamUnspec
+		return b
+	}
+	b[12] = v2CmdProxy
+	if srcIP := src.IP.To4(); srcIP != nil {
+		b[13] = v2FamTCP4
+		binary.BigEndian.PutUint16(b[14:], v2AddrLenTCP4)
+		b = append(b, srcIP...)
+		b = append(b, dst.IP.To4()...)
+	} else {
+		b[13] = v2FamTCP6
+		binary.BigEndian.PutUint16(b[14:], v2AddrLenTCP6)
+		b = append(b, src.IP.To16()...)
+		b = append(b, dst.IP.To16()...)
+	}
+	b = binary.BigEndian.AppendUint16(b, uint16(src.Port))
+	b = binary.BigEndian.AppendUint16(b, uint16(dst.Port))
+	return b
+}
+
+// addrs returns the source and destination addresses,
+// or nil if they cannot be represented in the same address family.
+func (h *Header) addrs() (src, dst *net.TCPAddr) {
+	if h.Src == nil || h.Dst == nil {
+		return nil, nil
+	}
+	srcV4, dstV4 := h.Src.IP.To4() != nil, h.Dst.IP.To4() != nil
+	if srcV4 != dstV4 {
+		return nil, nil
+	}
+	return h.Src, h.Dst
+}
+
+// WriteHeader writes a header of the version with the source and destination addresses of the client connection to w.
+func WriteHeader(w io.Writer, version Version, src, dst net.Addr) error {
+	h := &Header{Version: version}
+	h.Src, _ = src.(*net.TCPAddr)
+	h.Dst, _ = dst.(*net.TCPAddr)
+	_, err := w.Write(h.Format())
+	return err
+}
@@ -0,0 +1,81 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	expect "github.com/yusing/go-proxy/internal/utils/testing"
+)
+
+func TestReadHeaderV1(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\nGET / HTTP/1.1\r\n"))
+	h, err := ReadHeader(r)
+	expect.NoError(t, err)
+	expect.Equal(t, h.Version, V1)
+	expect.Equal(t, h.Src.String(), "192.168.0.1:56324")
+	expect.Equal(t, h.Dst.String(), "192.168.0.11:443")
+
+	rest, _ := io.ReadAll(r)
+	expect.Equal(t, string(rest), "GET / HTTP/1.1\r\n")
+}
+
+func TestReadHeaderV1Unknown(t *testing.T) {
+	h, err := ReadHeader(bufio.NewReader(strings.NewReader("PROXY UNKNOWN\r\n")))
+	expect.NoError(t, err)
+	expect.Nil(t, h.Src)
+}
+
+func TestReadHeaderInvalid(t *testing.T) {
+	_, err := ReadHeader(bufio.NewReader(strings.NewReader("PROXY TCP4 192.168.0.1\r\n")))
+	expect.ErrorIs(t, ErrInvalidHeader, err)
+}
+
+func TestReadHeaderNoHeader(t *testing.T) {
+	for _, data := range []string{"GET / HTTP/1.1\r\n", "PUT / HTTP/1.1\r\n", "\r\n"} {
+		r := bufio.NewReader(strings.NewReader(data))
+		_, err := ReadHeader(r)
+		expect.ErrorIs(t, ErrNoHeader, err)
+		// nothing consumed
+		rest, _ := io.ReadAll(r)
+		expect.Equal(t, string(rest), data)
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		src, dst *net.TCPAddr
+	}{
+		{"ipv4", &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1234}, &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 443}},
+		{"ipv6", &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 1234}, &net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 443}},
+		{"unknown", nil, nil},
+	}
+	for _, version := range []Version{V1, V2} {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var buf bytes.Buffer
+				expect.NoError(t, WriteHeader(&buf, version, tt.src, tt.dst))
+				buf.WriteString("payload")
+
+				r := bufio.NewReader(&buf)
+				h, err := ReadHeader(r)
+				expect.NoError(t, err)
+				expect.Equal(t, h.Version, version)
+				if tt.src == nil {
+					expect.Nil(t, h.Src)
+				} else {
+					expect.True(t, h.Src.IP.Equal(tt.src.IP))
+					expect.Equal(t, h.Src.Port, tt.src.Port)
+					expect.True(t, h.Dst.IP.Equal(tt.dst.IP))
+					expect.Equal(t, h.Dst.Port, tt.dst.Port)
+				}
+				rest, _ := io.ReadAll(r)
+				expect.Equal(t, string(rest), "payload")
+			})
+		}
+	}
+}
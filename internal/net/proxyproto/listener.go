@@ -0,0 +1,190 @@
+package proxyproto
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/yusing/go-proxy/internal/net/types"
+)
+
+type (
+	// Config is the config to accept PROXY protocol headers on a listener.
+	Config struct {
+		// TrustedCIDRs are the sources allowed to send PROXY protocol headers,
+		// e.g. the cloud load balancer in front of GoDoxy.
+		//
+		// Connections from other sources are accepted as is.
+		TrustedCIDRs []*types.CIDR `json:"trusted_cidrs" validate:"required,min=1"`
+		// HeaderTimeout is the timeout to read the header, default 5s.
+		HeaderTimeout time.Duration `json:"header_timeout,omitempty"`
+	}
+
+	// Listener reads the PROXY protocol header of connections from trusted sources,
+	// so RemoteAddr of accepted connections is the address of the real client.
+	//
+	// Headers are read in background, so a slow client does not block other connections.
+	Listener struct {
+		net.Listener
+
+		cfg *Config
+
+		conns     chan net.Conn
+		done      chan struct{}
+		closeOnce sync.Once
+		err       error
+
+		l *zerolog.Logger
+	}
+
+	// Conn is a connection with the addresses from the PROXY protocol header.
+	Conn struct {
+		net.Conn
+
+		r      *bufio.Reader
+		header *Header
+	}
+)
+
+const headerTimeoutDefault = 5 * time.Second
+
+// WrapListener wraps l to accept PROXY protocol headers.
+//
+// It returns l as is if cfg is nil.
+func (cfg *Config) WrapListener(l net.Listener, logger *zerolog.Logger) net.Listener {
+	if cfg == nil {
+		return l
+	}
+	pl := &Listener{
+		Listener: l,
+		cfg:      cfg,
+		conns:    make(chan net.Conn),
+		done:     make(chan struct{}),
+		l:        logger,
+	}
+	go pl.acceptLoop()
+	return pl
+}
+
+// Trusted returns whether the address is allowed to send PROXY protocol headers.
+func (cfg *Config) Trusted(addr net.Addr) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, cidr := range cfg.TrustedCIDRs {
+		if cidr.Contains(tcpAddr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+func (pl *Listener) acceptLoop() {
+	for {
+		conn, err := pl.Listener.Accept()
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			pl.err = err
+			pl.closeOnce.Do(func() { close(pl.done) })
+			return
+		}
+		if !pl.cfg.Trusted(conn.RemoteAddr()) {
+			pl.send(conn)
+			continue
+		}
+		go pl.readHeader(conn)
+	}
+}
+
+func (pl *Listener) readHeader(conn net.Conn) {
+	timeout := pl.cfg.HeaderTimeout
+	if timeout <= 0 {
+		timeout = headerTimeoutDefault
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(timeout))
+	c := &Conn{Conn: conn, r: bufio.NewReader(conn)}
+	h, err := ReadHeader(c.r)
+	_ = conn.SetReadDeadline(time.Time{})
+
+	var ne net.Error
+	switch {
+	case err == nil:
+		c.header = h
+	case errors.Is(err, ErrNoHeader):
+	case errors.As(err, &ne) && ne.Timeout() && c.r.Buffered() == 0:
+		// the client is waiting for the server to speak first
+	default:
+		pl.l.Debug().Err(err).Str("remote", conn.RemoteAddr().String()).Msg("failed to read PROXY protocol header")
+		conn.Close()
+		return
+	}
+	pl.send(c)
+}
+
+func (pl *Listener) send(conn net.Conn) {
+	select {
+	case pl.conns <- conn:
+	case <-pl.done:
+		conn.Close()
+	}
+}
+
+// Accept implements net.Listener.
+func (pl *Listener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-pl.conns:
+		return conn, nil
+	case <-pl.done:
+		if pl.err != nil {
+			return nil, pl.err
+		}
+		return nil, net.ErrClosed
+	}
+}
+
+// Close implements net.Listener.
+func (pl *Listener) Close() error {
+	pl.closeOnce.Do(func() { close(pl.done) })
+	return pl.Listener.Close()
+}
+
+// Header returns the PROXY protocol header of the connection, or nil if there is none.
+func (c *Conn) Header() *Header {
+	return c.header
+}
+
+// RemoteAddr returns the source address from the header if any,
+// or the address of the peer otherwise.
+func (c *Conn) RemoteAddr() net.Addr {
+	if c.header != nil && c.header.Src != nil {
+		return c.header.Src
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// LocalAddr returns the destination address from the header if any,
+// or the local address of the connection otherwise.
+func (c *Conn) LocalAddr() net.Addr {
+	if c.header != nil && c.header.Dst != nil {
+		return c.header.Dst
+	}
+	return c.Conn.LocalAddr()
+}
+
+func (c *Conn) Read(b []byte) (int, error) {
+	if c.r != nil {
+		if c.r.Buffered() > 0 {
+			return c.r.Read(b)
+		}
+		// header consumed, read from the connection directly
+		c.r = nil
+	}
+	return c.Conn.Read(b)
+}
@@ -10,6 +10,7 @@ import (
 	gphttp "github.com/yusing/go-proxy/internal/net/gphttp"
 	"github.com/yusing/go-proxy/internal/net/gphttp/middleware"
 	"github.com/yusing/go-proxy/internal/route/routes"
+	route "github.com/yusing/go-proxy/internal/route/types"
 	"github.com/yusing/go-proxy/internal/task"
 	"github.com/yusing/go-proxy/internal/watcher/health"
 	"github.com/yusing/go-proxy/internal/watcher/health/monitor"
@@ -28,10 +29,6 @@ type (
 	}
 )
 
-func handler(root string) http.Handler {
-	return http.FileServer(http.Dir(root))
-}
-
 func NewFileServer(base *Route) (*FileServer, gperr.Error) {
 	s := &FileServer{Route: base}
 
@@ -40,7 +37,13 @@ func NewFileServer(base *Route) (*FileServer, gperr.Error) {
 		return nil, gperr.New("`root` must be an absolute path")
 	}
 
-	s.handler = handler(s.Root)
+	if s.FileServerConfig == nil {
+		s.FileServerConfig = new(route.FileServerConfig)
+		if err := s.FileServerConfig.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	s.handler = newFileServerHandler(s.Root, s.FileServerConfig)
 
 	if len(s.Middlewares) > 0 {
 		mid, err := middleware.BuildMiddlewareFromMap(s.Alias, s.Middlewares)
@@ -96,6 +99,14 @@ func (s *FileServer) Start(parent task.Parent) gperr.Error {
 		}
 	}
 
+	if s.UsePathRoute() {
+		if err := addToPathRouter(parent, s.task, s, s.Route); err != nil {
+			s.task.Finish(err)
+			return err
+		}
+		return nil
+	}
+
 	routes.HTTP.Add(s)
 	s.task.OnCancel("entrypoint_remove_route", func() {
 		routes.HTTP.Del(s)
@@ -114,12 +125,31 @@ func (s *FileServer) Finish(reason any) {
 
 // ServeHTTP implements http.Handler.
 func (s *FileServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	s.handler.ServeHTTP(w, req)
-	if s.accessLogger != nil {
-		s.accessLogger.Log(req, req.Response)
+	serveWithAccessLog(s.handler, s.accessLogger, w, req)
+}
+
+// serveWithAccessLog serves the request with h,
+// and logs it with the status and size of the response if logger is not nil.
+//
+// req.Response is nil for server requests, so the response is recorded from the writer.
+func serveWithAccessLog(h http.Handler, logger *accesslog.AccessLogger, w http.ResponseWriter, req *http.Request) {
+	if logger == nil {
+		h.ServeHTTP(w, req)
+		return
 	}
+	rw := gphttp.NewModifyResponseWriter(w, req, nil)
+	h.ServeHTTP(rw, req)
+	logger.Log(req, &http.Response{
+		StatusCode:    rw.StatusCode(),
+		Header:        rw.Header(),
+		ContentLength: int64(rw.Size()),
+		Request:       req,
+	})
 }
 
 func (s *FileServer) HealthMonitor() health.HealthMonitor {
+	if s.Health == nil {
+		return nil
+	}
 	return s.Health
 }
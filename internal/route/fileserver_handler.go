@@ -0,0 +1,299 @@
+package route
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	route "github.com/yusing/go-proxy/internal/route/types"
+)
+
+// fileServerHandler serves files under root like http.FileServer,
+// with the options of route.FileServerConfig.
+type fileServerHandler struct {
+	root http.FileSystem
+	cfg  *route.FileServerConfig
+
+	// etags caches strong ETags of files by path.
+	etags sync.Map // map[string]etagEntry
+}
+
+type etagEntry struct {
+	modTime time.Time
+	size    int64
+	etag    string
+}
+
+// precompressedEncodings are the content encodings of precompressed siblings, in order of preference.
+var precompressedEncodings = []struct {
+	encoding string
+	ext      string
+}{
+	{"br", ".br"},
+	{"zstd", ".zst"},
+	{"gzip", ".gz"},
+}
+
+func newFileServerHandler(root string, cfg *route.FileServerConfig) *fileServerHandler {
+	return &fileServerHandler{root: http.Dir(root), cfg: cfg}
+}
+
+func (h *fileServerHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upath := r.URL.Path
+	if !strings.HasPrefix(upath, "/") {
+		upath = "/" + upath
+	}
+	name := path.Clean(upath)
+
+	if h.cfg.HideDotfiles && hasDotSegment(name) {
+		http.NotFound(w, r)
+		return
+	}
+
+	f, err := h.root.Open(name)
+	if err != nil {
+		h.notFound(w, r, name, err)
+		return
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		h.notFound(w, r, name, err)
+		return
+	}
+
+	if stat.IsDir() {
+		if !strings.HasSuffix(upath, "/") {
+			localRedirect(w, r, path.Base(upath)+"/")
+			return
+		}
+		if index, indexStat, ok := h.openIndex(name); ok {
+			defer index.Close()
+			h.serveFile(w, r, path.Join(name, indexStat.Name()), index, indexStat)
+			return
+		}
+		if h.cfg.DisableListing {
+			h.notFound(w, r, name, fs.ErrNotExist)
+			return
+		}
+		h.dirList(w, r, f)
+		return
+	}
+
+	// like http.FileServer, do not expose the index file under its own name
+	if slices.Contains(h.cfg.IndexFiles, stat.Name()) && strings.HasSuffix(upath, "/"+stat.Name()) {
+		localRedirect(w, r, "./")
+		return
+	}
+	h.serveFile(w, r, name, f, stat)
+}
+
+// notFound responds with the root index file for SPA routes, otherwise an error by err.
+func (h *fileServerHandler) notFound(w http.ResponseWriter, r *http.Request, name string, err error) {
+	if h.cfg.SPA && (r.Method == http.MethodGet || r.Method == http.MethodHead) && path.Ext(name) == "" {
+		if index, indexStat, ok := h.openIndex("/"); ok {
+			defer index.Close()
+			h.serveFile(w, r, "/"+indexStat.Name(), index, indexStat)
+			return
+		}
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		http.NotFound(w, r)
+	case errors.Is(err, fs.ErrPermission):
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+	default:
+		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// openIndex opens the first existing index file of the directory.
+func (h *fileServerHandler) openIndex(dir string) (http.File, fs.FileInfo, bool) {
+	for _, index := range h.cfg.IndexFiles {
+		f, err := h.root.Open(path.Join(dir, index))
+		if err != nil {
+			continue
+		}
+		stat, err := f.Stat()
+		if err != nil || stat.IsDir() {
+			f.Close()
+			continue
+		}
+		return f, stat, true
+	}
+	return nil, nil, false
+}
+
+// serveFile serves the file at name, or its precompressed sibling if accepted.
+func (h *fileServerHandler) serveFile(w http.ResponseWriter, r *http.Request, name string, f http.File, stat fs.FileInfo) {
+	header := w.Header()
+	if cc := h.cfg.CacheControlOf(name); cc != "" {
+		header.Set("Cache-Control", cc)
+	}
+
+	if h.cfg.Precompressed {
+		header.Add("Vary", "Accept-Encoding")
+		if cf, cstat, encoding, ok := h.openPrecompressed(r, name); ok {
+			defer cf.Close()
+			// the content type is of the original file, not the compressed one
+			if header.Get("Content-Type") == "" {
+				ctype, err := contentType(name, f)
+				if err != nil {
+					http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+					return
+				}
+				header.Set("Content-Type", ctype)
+			}
+			header.Set("Content-Encoding", encoding)
+			name, f, stat = name+path.Ext(cstat.Name()), cf, cstat
+		}
+	}
+
+	if h.cfg.ETag {
+		etag, err := h.etag(name, f, stat)
+		if err != nil {
+			http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		header.Set("Etag", etag)
+	}
+
+	http.ServeContent(w, r, stat.Name(), stat.ModTime(), f)
+}
+
+// openPrecompressed opens the most preferred precompressed sibling of the file accepted by the client.
+func (h *fileServerHandler) openPrecompressed(r *http.Request, name string) (http.File, fs.FileInfo, string, bool) {
+	accept := r.Header.Get("Accept-Encoding")
+	if accept == "" {
+		return nil, nil, "", false
+	}
+	for _, enc := range precompressedEncodings {
+		if !acceptsEncoding(accept, enc.encoding) {
+			continue
+		}
+		f, err := h.root.Open(name + enc.ext)
+		if err != nil {
+			continue
+		}
+		stat, err := f.Stat()
+		if err != nil || stat.IsDir() {
+			f.Close()
+			continue
+		}
+		return f, stat, enc.encoding, true
+	}
+	return nil, nil, "", false
+}
+
+// etag returns the strong ETag of the file, computed from its content
+// and cached until the file is modified.
+func (h *fileServerHandler) etag(name string, f http.File, stat fs.FileInfo) (string, error) {
+	if v, ok := h.etags.Load(name); ok {
+		e := v.(etagEntry)
+		if e.modTime.Equal(stat.ModTime()) && e.size == stat.Size() {
+			return e.etag, nil
+		}
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := `"` + base64.RawURLEncoding.EncodeToString(hash.Sum(nil)[:16]) + `"`
+	h.etags.Store(name, etagEntry{modTime: stat.ModTime(), size: stat.Size(), etag: etag})
+	return etag, nil
+}
+
+func (h *fileServerHandler) dirList(w http.ResponseWriter, r *http.Request, f http.File) {
+	entries, err := f.Readdir(-1)
+	if err != nil {
+		http.Error(w, "Error reading directory", http.StatusInternalServerError)
+		return
+	}
+	slices.SortFunc(entries, func(a, b fs.FileInfo) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n")
+	for _, entry := range entries {
+		name := entry.Name()
+		if h.cfg.HideDotfiles && strings.HasPrefix(name, ".") {
+			continue
+		}
+		if entry.IsDir() {
+			name += "/"
+		}
+		u := url.URL{Path: name}
+		fmt.Fprintf(w, "<a href=\"%s\">%s</a>\n", u.String(), html.EscapeString(name))
+	}
+	fmt.Fprintf(w, "</pre>\n")
+}
+
+// hasDotSegment returns whether any segment of the cleaned path starts with a dot.
+func hasDotSegment(name string) bool {
+	for seg := range strings.SplitSeq(name, "/") {
+		if strings.HasPrefix(seg, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsEncoding returns whether the Accept-Encoding header accepts the encoding,
+// i.e. it is listed without `q=0`.
+func acceptsEncoding(accept, encoding string) bool {
+	for part := range strings.SplitSeq(accept, ",") {
+		enc, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(enc), encoding) {
+			continue
+		}
+		q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !ok {
+			return true
+		}
+		v, err := strconv.ParseFloat(q, 64)
+		return err != nil || v > 0
+	}
+	return false
+}
+
+// contentType returns the content type of the file by its extension,
+// or by sniffing its content.
+func contentType(name string, f http.File) (string, error) {
+	if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+		return ctype, nil
+	}
+	var buf [512]byte
+	n, _ := io.ReadFull(f, buf[:])
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// localRedirect redirects to the path relative to the request path, keeping the query.
+func localRedirect(w http.ResponseWriter, r *http.Request, newPath string) {
+	if q := r.URL.RawQuery; q != "" {
+		newPath += "?" + q
+	}
+	w.Header().Set("Location", newPath)
+	w.WriteHeader(http.StatusMovedPermanently)
+}
@@ -8,8 +8,10 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	route "github.com/yusing/go-proxy/internal/route/types"
 	. "github.com/yusing/go-proxy/internal/utils/testing"
 )
 
@@ -120,3 +122,203 @@ func TestPathTraversalAttack(t *testing.T) {
 		})
 	}
 }
+
+func newTestFileServer(t *testing.T, cfg *route.FileServerConfig, files map[string]string) *httptest.Server {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(root, name)
+		ExpectNoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		ExpectNoError(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+	ExpectNoError(t, cfg.Validate())
+	fs, err := NewFileServer(&Route{Root: root, FileServerConfig: cfg})
+	ExpectNoError(t, err)
+	ts := httptest.NewServer(fs.handler)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func getFile(t *testing.T, ts *httptest.Server, p string, header http.Header) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, ts.URL+p, nil)
+	ExpectNoError(t, err)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	// do not follow redirects, and do not let the transport decompress the body
+	client := &http.Client{
+		Transport: &http.Transport{DisableCompression: true},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(req)
+	ExpectNoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	ExpectNoError(t, err)
+	return resp, string(body)
+}
+
+func TestFileServerSPA(t *testing.T) {
+	ts := newTestFileServer(t, &route.FileServerConfig{SPA: true}, map[string]string{
+		"index.html":    "app",
+		"assets/app.js": "js",
+	})
+
+	resp, body := getFile(t, ts, "/users/1", nil)
+	ExpectEqual(t, resp.StatusCode, http.StatusOK)
+	ExpectEqual(t, body, "app")
+
+	resp, body = getFile(t, ts, "/assets/app.js", nil)
+	ExpectEqual(t, resp.StatusCode, http.StatusOK)
+	ExpectEqual(t, body, "js")
+
+	resp, _ = getFile(t, ts, "/assets/missing.js", nil)
+	ExpectEqual(t, resp.StatusCode, http.StatusNotFound)
+
+	t.Run("disabled", func(t *testing.T) {
+		ts := newTestFileServer(t, &route.FileServerConfig{}, map[string]string{"index.html": "app"})
+		resp, _ := getFile(t, ts, "/users/1", nil)
+		ExpectEqual(t, resp.StatusCode, http.StatusNotFound)
+	})
+}
+
+func TestFileServerPrecompressed(t *testing.T) {
+	ts := newTestFileServer(t, &route.FileServerConfig{Precompressed: true}, map[string]string{
+		"app.js":       "plain",
+		"app.js.br":    "brotli",
+		"app.js.gz":    "gzip",
+		"style.css":    "plain",
+		"style.css.gz": "gzip",
+	})
+
+	resp, body := getFile(t, ts, "/app.js", http.Header{"Accept-Encoding": {"gzip, deflate, br"}})
+	ExpectEqual(t, body, "brotli")
+	ExpectEqual(t, resp.Header.Get("Content-Encoding"), "br")
+	ExpectEqual(t, resp.Header.Get("Content-Type"), "text/javascript; charset=utf-8")
+	ExpectEqual(t, resp.Header.Get("Vary"), "Accept-Encoding")
+
+	resp, body = getFile(t, ts, "/app.js", http.Header{"Accept-Encoding": {"gzip, br;q=0"}})
+	ExpectEqual(t, body, "gzip")
+	ExpectEqual(t, resp.Header.Get("Content-Encoding"), "gzip")
+
+	resp, body = getFile(t, ts, "/style.css", http.Header{"Accept-Encoding": {"br"}})
+	ExpectEqual(t, body, "plain")
+	ExpectEqual(t, resp.Header.Get("Content-Encoding"), "")
+	ExpectEqual(t, resp.Header.Get("Content-Type"), "text/css; charset=utf-8")
+
+	resp, body = getFile(t, ts, "/app.js", nil)
+	ExpectEqual(t, body, "plain")
+	ExpectEqual(t, resp.Header.Get("Content-Encoding"), "")
+}
+
+func TestFileServerCacheControl(t *testing.T) {
+	ts := newTestFileServer(t, &route.FileServerConfig{
+		CacheControl: map[string]string{
+			"/assets/*": "public, max-age=31536000, immutable",
+			"*.html":    "no-cache",
+		},
+	}, map[string]string{
+		"index.html":        "app",
+		"assets/app.js":     "js",
+		"assets/about.html": "about",
+		"robots.txt":        "robots",
+	})
+
+	resp, _ := getFile(t, ts, "/", nil)
+	ExpectEqual(t, resp.Header.Get("Cache-Control"), "no-cache")
+	resp, _ = getFile(t, ts, "/assets/app.js", nil)
+	ExpectEqual(t, resp.Header.Get("Cache-Control"), "public, max-age=31536000, immutable")
+	// the longer pattern wins
+	resp, _ = getFile(t, ts, "/assets/about.html", nil)
+	ExpectEqual(t, resp.Header.Get("Cache-Control"), "public, max-age=31536000, immutable")
+	resp, _ = getFile(t, ts, "/robots.txt", nil)
+	ExpectEqual(t, resp.Header.Get("Cache-Control"), "")
+
+	ExpectHasError(t, (&route.FileServerConfig{CacheControl: map[string]string{"[": "no-cache"}}).Validate())
+}
+
+func TestFileServerETag(t *testing.T) {
+	ts := newTestFileServer(t, &route.FileServerConfig{ETag: true}, map[string]string{
+		"a.txt": "same",
+		"b.txt": "same",
+		"c.txt": "different",
+	})
+
+	resp, _ := getFile(t, ts, "/a.txt", nil)
+	etag := resp.Header.Get("Etag")
+	ExpectTrue(t, strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, `W/`))
+
+	resp, _ = getFile(t, ts, "/b.txt", nil)
+	ExpectEqual(t, resp.Header.Get("Etag"), etag)
+	resp, _ = getFile(t, ts, "/c.txt", nil)
+	ExpectTrue(t, resp.Header.Get("Etag") != etag)
+
+	resp, body := getFile(t, ts, "/a.txt", http.Header{"If-None-Match": {etag}})
+	ExpectEqual(t, resp.StatusCode, http.StatusNotModified)
+	ExpectEqual(t, body, "")
+}
+
+func TestFileServerIndexFiles(t *testing.T) {
+	ts := newTestFileServer(t, &route.FileServerConfig{IndexFiles: []string{"index.htm", "default.html"}}, map[string]string{
+		"docs/default.html": "default",
+		"blog/index.htm":    "blog",
+		"blog/default.html": "default",
+	})
+
+	_, body := getFile(t, ts, "/docs/", nil)
+	ExpectEqual(t, body, "default")
+	_, body = getFile(t, ts, "/blog/", nil)
+	ExpectEqual(t, body, "blog")
+
+	resp, _ := getFile(t, ts, "/docs", nil)
+	ExpectEqual(t, resp.StatusCode, http.StatusMovedPermanently)
+	ExpectEqual(t, resp.Header.Get("Location"), "docs/")
+
+	ExpectHasError(t, (&route.FileServerConfig{IndexFiles: []string{"docs/index.html"}}).Validate())
+}
+
+func TestFileServerListing(t *testing.T) {
+	files := map[string]string{
+		"docs/readme.txt": "readme",
+		"docs/.secret":    "secret",
+	}
+
+	ts := newTestFileServer(t, &route.FileServerConfig{}, files)
+	resp, body := getFile(t, ts, "/docs/", nil)
+	ExpectEqual(t, resp.StatusCode, http.StatusOK)
+	ExpectTrue(t, strings.Contains(body, "readme.txt"))
+	ExpectTrue(t, strings.Contains(body, ".secret"))
+
+	ts = newTestFileServer(t, &route.FileServerConfig{HideDotfiles: true}, files)
+	_, body = getFile(t, ts, "/docs/", nil)
+	ExpectTrue(t, strings.Contains(body, "readme.txt"))
+	ExpectTrue(t, !strings.Contains(body, ".secret"))
+
+	ts = newTestFileServer(t, &route.FileServerConfig{DisableListing: true}, files)
+	resp, _ = getFile(t, ts, "/docs/", nil)
+	ExpectEqual(t, resp.StatusCode, http.StatusNotFound)
+	resp, _ = getFile(t, ts, "/docs/readme.txt", nil)
+	ExpectEqual(t, resp.StatusCode, http.StatusOK)
+}
+
+func TestFileServerHideDotfiles(t *testing.T) {
+	files := map[string]string{
+		".env":        "secret",
+		".git/config": "secret",
+		"index.html":  "app",
+	}
+
+	ts := newTestFileServer(t, &route.FileServerConfig{HideDotfiles: true, SPA: true}, files)
+	for _, p := range []string{"/.env", "/.git/config", "/.git/", "/docs/../.env"} {
+		resp, body := getFile(t, ts, p, nil)
+		ExpectEqual(t, resp.StatusCode, http.StatusNotFound)
+		ExpectTrue(t, body != "secret")
+	}
+
+	ts = newTestFileServer(t, &route.FileServerConfig{}, files)
+	_, body := getFile(t, ts, "/.env", nil)
+	ExpectEqual(t, body, "secret")
+}
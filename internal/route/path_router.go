@@ -0,0 +1,198 @@
+package route
+
+import (
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/yusing/go-proxy/internal/gperr"
+	"github.com/yusing/go-proxy/internal/net/gphttp/httpheaders"
+	"github.com/yusing/go-proxy/internal/route/routes"
+	route "github.com/yusing/go-proxy/internal/route/types"
+	"github.com/yusing/go-proxy/internal/task"
+	"github.com/yusing/go-proxy/internal/utils/atomic"
+	"github.com/yusing/go-proxy/internal/watcher/health"
+)
+
+type (
+	// PathRouter is a HTTP route composed of routes by path prefix,
+	// registered under the link alias of its routes.
+	PathRouter struct {
+		*Route
+
+		// routes sorted by prefix length, longest first.
+		//
+		// Replaced instead of modified, so requests are routed without locking.
+		routes atomic.Value[[]*prefixRoute]
+
+		task *task.Task
+	}
+
+	prefixRoute struct {
+		route.PathRouteConfig
+		route routes.HTTPRoute
+	}
+)
+
+// pathRoutersMu serializes adding and removing routes of path routers.
+var pathRoutersMu sync.Mutex
+
+// addToPathRouter adds the route to the path router of its link,
+// creating the path router if it does not exist.
+//
+// The route is removed from the path router when t is canceled,
+// and the path router is removed and finished when it has no routes.
+func addToPathRouter(parent task.Parent, t *task.Task, r routes.HTTPRoute, base *Route) gperr.Error {
+	pathRoutersMu.Lock()
+	defer pathRoutersMu.Unlock()
+
+	cfg := base.PathRoute
+	var router *PathRouter
+	if existing, ok := routes.HTTP.Get(cfg.Link); ok {
+		router, ok = existing.(*PathRouter)
+		if !ok {
+			return gperr.Errorf("route already exists: from provider %s and %s", existing.ProviderName(), base.ProviderName())
+		}
+		for _, pr := range router.routes.Load() {
+			if pr.Prefix == cfg.Prefix {
+				return gperr.Errorf("path prefix %s of %s already exists: from route %s of provider %s and %s of provider %s",
+					cfg.Prefix, cfg.Link, pr.route.Name(), pr.route.ProviderName(), base.Name(), base.ProviderName())
+			}
+		}
+	} else {
+		router = &PathRouter{
+			Route: &Route{
+				Alias:    cfg.Link,
+				Homepage: base.Homepage,
+			},
+		}
+		_ = router.Start(parent) // always return nil
+		routes.HTTP.Add(router)
+	}
+
+	router.add(&prefixRoute{PathRouteConfig: *cfg, route: r})
+	t.OnCancel("path_router_remove_route", func() {
+		pathRoutersMu.Lock()
+		defer pathRoutersMu.Unlock()
+		if router.remove(r) == 0 {
+			routes.HTTP.Del(router)
+			router.Finish(nil)
+		}
+	})
+	return nil
+}
+
+func (router *PathRouter) add(pr *prefixRoute) {
+	prs := append(slices.Clone(router.routes.Load()), pr)
+	slices.SortStableFunc(prs, func(a, b *prefixRoute) int {
+		return len(b.Prefix) - len(a.Prefix)
+	})
+	router.routes.Store(prs)
+}
+
+// remove removes the route and returns the number of remaining routes.
+func (router *PathRouter) remove(r routes.HTTPRoute) int {
+	prs := slices.DeleteFunc(slices.Clone(router.routes.Load()), func(pr *prefixRoute) bool {
+		return pr.route == r
+	})
+	router.routes.Store(prs)
+	return len(prs)
+}
+
+// match returns the route with the longest prefix matching the path.
+func (router *PathRouter) match(path string) *prefixRoute {
+	for _, pr := range router.routes.Load() {
+		if matchPathPrefix(path, pr.Prefix) {
+			return pr
+		}
+	}
+	return nil
+}
+
+// matchPathPrefix returns whether the path is under the prefix,
+// e.g. `/api` matches `/api` and `/api/v1` but not `/apis`.
+func matchPathPrefix(path, prefix string) bool {
+	if prefix == "/" {
+		return true
+	}
+	rest, ok := strings.CutPrefix(path, prefix)
+	return ok && (rest == "" || rest[0] == '/')
+}
+
+// stripPathPrefix returns a shallow copy of the request with the prefix removed from the path.
+func stripPathPrefix(req *http.Request, prefix string) *http.Request {
+	r := new(http.Request)
+	*r = *req
+	u := *req.URL
+	r.URL = &u
+	r.URL.Path = ensureLeadingSlash(strings.TrimPrefix(req.URL.Path, prefix))
+	if req.URL.RawPath != "" {
+		r.URL.RawPath = ensureLeadingSlash(strings.TrimPrefix(req.URL.RawPath, prefix))
+	}
+	r.Header = req.Header.Clone()
+	r.Header.Set(httpheaders.HeaderXForwardedPrefix, prefix)
+	return r
+}
+
+// cleanPath returns the cleaned path like path.Clean, keeping the trailing slash.
+func cleanPath(p string) string {
+	cleaned := path.Clean(ensureLeadingSlash(p))
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+func ensureLeadingSlash(path string) string {
+	if !strings.HasPrefix(path, "/") {
+		return "/" + path
+	}
+	return path
+}
+
+// ServeHTTP implements http.Handler.
+func (router *PathRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// `..` and duplicate slashes must not match another prefix,
+	// the cleaned path is forwarded so the upstream sees the path that was matched
+	if cleaned := cleanPath(req.URL.Path); cleaned != req.URL.Path {
+		req = req.Clone(req.Context())
+		req.URL.Path, req.URL.RawPath = cleaned, ""
+	}
+	pr := router.match(req.URL.Path)
+	if pr == nil {
+		http.NotFound(w, req)
+		return
+	}
+	if pr.StripPrefix && pr.Prefix != "/" {
+		req = stripPathPrefix(req, pr.Prefix)
+	}
+	pr.route.ServeHTTP(w, req)
+}
+
+// Start implements task.TaskStarter.
+//
+// The path router is started with its first route and finished with its last route.
+func (router *PathRouter) Start(parent task.Parent) gperr.Error {
+	router.task = parent.Subtask("path_router."+router.Alias, false)
+	return nil
+}
+
+// Task implements task.TaskStarter.
+func (router *PathRouter) Task() *task.Task {
+	return router.task
+}
+
+// Finish implements task.TaskFinisher.
+func (router *PathRouter) Finish(reason any) {
+	router.task.Finish(reason)
+}
+
+// HealthMonitor returns the health monitor of the route mounted at `/`, if any.
+func (router *PathRouter) HealthMonitor() health.HealthMonitor {
+	if pr := router.match("/"); pr != nil && pr.Prefix == "/" {
+		return pr.route.HealthMonitor()
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package route
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/yusing/go-proxy/internal/net/gphttp/httpheaders"
+	"github.com/yusing/go-proxy/internal/route/routes"
+	route "github.com/yusing/go-proxy/internal/route/types"
+	"github.com/yusing/go-proxy/internal/task"
+	expect "github.com/yusing/go-proxy/internal/utils/testing"
+)
+
+func TestMatchPathPrefix(t *testing.T) {
+	tests := []struct {
+		path, prefix string
+		want         bool
+	}{
+		{"/", "/", true},
+		{"/api", "/", true},
+		{"/api", "/api", true},
+		{"/api/v1", "/api", true},
+		{"/apis", "/api", false},
+		{"/", "/api", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path+" "+tt.prefix, func(t *testing.T) {
+			expect.Equal(t, matchPathPrefix(tt.path, tt.prefix), tt.want)
+		})
+	}
+}
+
+// echoRoute responds with its name, the request path and the X-Forwarded-Prefix header.
+func echoRoute(name string) *StaticRoute {
+	return &StaticRoute{
+		Route: &Route{Alias: name},
+		handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, name+" "+r.URL.Path+" "+r.Header.Get(httpheaders.HeaderXForwardedPrefix))
+		}),
+	}
+}
+
+func TestPathRouterServeHTTP(t *testing.T) {
+	router := &PathRouter{Route: &Route{Alias: "app"}}
+	router.add(&prefixRoute{PathRouteConfig: route.PathRouteConfig{Prefix: "/"}, route: echoRoute("root")})
+	router.add(&prefixRoute{PathRouteConfig: route.PathRouteConfig{Prefix: "/api", StripPrefix: true}, route: echoRoute("api")})
+	router.add(&prefixRoute{PathRouteConfig: route.PathRouteConfig{Prefix: "/api/v2"}, route: echoRoute("v2")})
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/", "root / "},
+		{"/apis", "root /apis "},
+		{"/api", "api / /api"},
+		{"/api/v1/users", "api /v1/users /api"},
+		{"/api/v2/users", "v2 /api/v2/users "},
+		// cleaned before matching, keeping the trailing slash
+		{"/api/v2/../v1/users/", "api /v1/users/ /api"},
+		{"/apis/../api/v2/users", "v2 /api/v2/users "},
+		{"/api/v2/..", "api / /api"},
+		{"//api//v2/users", "v2 /api/v2/users "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://app.example.com"+tt.path, nil))
+			expect.Equal(t, w.Body.String(), tt.want)
+		})
+	}
+
+	t.Run("no match", func(t *testing.T) {
+		router := &PathRouter{Route: &Route{Alias: "app"}}
+		router.add(&prefixRoute{PathRouteConfig: route.PathRouteConfig{Prefix: "/api"}, route: echoRoute("api")})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://app.example.com/", nil))
+		expect.Equal(t, w.Code, http.StatusNotFound)
+	})
+}
+
+func TestAddToPathRouterConflict(t *testing.T) {
+	newRoute := func(name, prefix string) *StaticRoute {
+		r := echoRoute(name)
+		r.PathRoute = &route.PathRouteConfig{Link: "path-router-conflict", Prefix: prefix}
+		return r
+	}
+	parent := task.RootTask("test", false)
+	defer parent.Finish(nil)
+
+	a := newRoute("a", "/api")
+	expect.NoError(t, addToPathRouter(parent, parent.Subtask("a", false), a, a.Route))
+	b := newRoute("b", "/")
+	expect.NoError(t, addToPathRouter(parent, parent.Subtask("b", false), b, b.Route))
+	c := newRoute("c", "/api")
+	err := addToPathRouter(parent, parent.Subtask("c", false), c, c.Route)
+	expect.ErrorContains(t, err, "path prefix /api of path-router-conflict already exists")
+}
+
+func TestPathRouterLifecycle(t *testing.T) {
+	parent := task.RootTask("test", false)
+	defer parent.Finish(nil)
+
+	// a file server without health check at `/`
+	fs := &FileServer{Route: &Route{Alias: "files"}}
+	fs.PathRoute = &route.PathRouteConfig{Link: "path-router-lifecycle", Prefix: "/"}
+	fsTask := parent.Subtask("files", false)
+	expect.NoError(t, addToPathRouter(parent, fsTask, fs, fs.Route))
+
+	r, ok := routes.HTTP.Get("path-router-lifecycle")
+	expect.True(t, ok)
+	router := r.(*PathRouter)
+	expect.True(t, router.Task() != nil)
+	expect.True(t, router.HealthMonitor() == nil)
+
+	fsTask.FinishAndWait(nil)
+	_, ok = routes.HTTP.Get("path-router-lifecycle")
+	expect.False(t, ok)
+	<-router.Task().Context().Done()
+}
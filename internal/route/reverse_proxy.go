@@ -1,8 +1,12 @@
 package route
 
 import (
-	"crypto/tls"
+	"bytes"
+	"context"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
 
 	"github.com/yusing/go-proxy/agent/pkg/agent"
 	"github.com/yusing/go-proxy/agent/pkg/agentproxy"
@@ -15,8 +19,10 @@ import (
 	loadbalance "github.com/yusing/go-proxy/internal/net/gphttp/loadbalancer/types"
 	"github.com/yusing/go-proxy/internal/net/gphttp/middleware"
 	"github.com/yusing/go-proxy/internal/net/gphttp/reverseproxy"
+	"github.com/yusing/go-proxy/internal/net/proxyproto"
 	"github.com/yusing/go-proxy/internal/net/types"
 	"github.com/yusing/go-proxy/internal/route/routes"
+	route "github.com/yusing/go-proxy/internal/route/types"
 	"github.com/yusing/go-proxy/internal/task"
 	"github.com/yusing/go-proxy/internal/watcher/health"
 	"github.com/yusing/go-proxy/internal/watcher/health/monitor"
@@ -48,9 +54,38 @@ func NewReverseProxyRoute(base *Route) (*ReveseProxyRoute, gperr.Error) {
 		trans = a.Transport()
 		proxyURL = types.NewURL(agent.HTTPProxyURL)
 	} else {
-		trans = gphttp.NewTransport()
-		if httpConfig.NoTLSVerify {
-			trans.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		switch base.Scheme {
+		case route.SchemeH2C, route.SchemeGRPC:
+			trans = gphttp.NewH2CTransport()
+		case route.SchemeGRPCS:
+			trans = gphttp.NewHTTP2Transport()
+		default:
+			trans = gphttp.NewTransport()
+		}
+		if base.Scheme.IsGRPC() {
+			// streaming RPCs may not send response headers until the first message
+			trans.ResponseHeaderTimeout = 0
+		}
+		transOpts := httpConfig.TransportOptions()
+		transOpts.Apply(trans)
+		if socket := base.UnixSocket(); socket != "" {
+			// requests are sent to localhost, the connections go to the socket
+			dialer := transOpts.Dialer()
+			trans.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", socket)
+			}
+			proxyURL = types.NewURL(&url.URL{Scheme: string(base.Scheme.HTTPScheme()), Host: DefaultHost})
+		} else if scheme := base.Scheme.HTTPScheme(); scheme != base.Scheme {
+			u := base.ProxyURL.URL
+			u.Scheme = string(scheme)
+			proxyURL = types.NewURL(&u)
+		}
+		if tlsOpts := httpConfig.UpstreamTLSOptions(); !tlsOpts.IsZero() {
+			tlsConfig, err := tlsOpts.TLSConfig()
+			if err != nil {
+				return nil, gperr.Wrap(err, "invalid upstream TLS config")
+			}
+			trans.TLSClientConfig = tlsConfig
 		}
 		if httpConfig.ResponseHeaderTimeout > 0 {
 			trans.ResponseHeaderTimeout = httpConfig.ResponseHeaderTimeout
@@ -62,6 +97,7 @@ func NewReverseProxyRoute(base *Route) (*ReveseProxyRoute, gperr.Error) {
 
 	service := base.Name()
 	rp := reverseproxy.NewReverseProxy(service, proxyURL, trans)
+	rp.HostHeader = httpConfig.HostHeader
 
 	if len(base.Middlewares) > 0 {
 		err := middleware.PatchReverseProxy(rp, base.Middlewares)
@@ -71,11 +107,24 @@ func NewReverseProxyRoute(base *Route) (*ReveseProxyRoute, gperr.Error) {
 	}
 
 	if a != nil {
+		// files are read by the agent
+		if err := httpConfig.UpstreamTLSOptions().Validate(); err != nil {
+			return nil, gperr.Wrap(err, "invalid upstream TLS config")
+		}
+		if err := httpConfig.TransportOptions().Validate(); err != nil {
+			return nil, gperr.Wrap(err, "invalid transport config")
+		}
 		headers := &agentproxy.AgentProxyHeaders{
 			Host:                  base.ProxyURL.Host,
 			IsHTTPS:               base.ProxyURL.Scheme == "https",
 			SkipTLSVerify:         httpConfig.NoTLSVerify,
+			SSLServerName:         httpConfig.SSLServerName,
+			SSLCA:                 httpConfig.SSLCA,
+			SSLCert:               httpConfig.SSLCert,
+			SSLKey:                httpConfig.SSLKey,
+			SSLProtocols:          httpConfig.SSLProtocols,
 			ResponseHeaderTimeout: int(httpConfig.ResponseHeaderTimeout.Seconds()),
+			TransportOptions:      *httpConfig.TransportOptions(),
 		}
 		ori := rp.HandlerFunc
 		rp.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
@@ -84,6 +133,36 @@ func NewReverseProxyRoute(base *Route) (*ReveseProxyRoute, gperr.Error) {
 		}
 	}
 
+	if pp := base.ProxyProtocol; pp != nil && pp.Send != 0 {
+		trans.DialContext = proxyproto.Dialer(pp.Send, trans.DialContext)
+		// the header is sent per connection, so connections cannot be reused for other clients
+		trans.DisableKeepAlives = true
+		ori := rp.HandlerFunc
+		rp.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+			ori(w, proxyproto.WithClientAddr(r))
+		}
+	}
+
+	if timeout := httpConfig.RequestTimeout; timeout > 0 {
+		ori := rp.HandlerFunc
+		rp.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			ori(w, r.WithContext(ctx))
+		}
+	}
+
+	if httpConfig.BufferRequestBody {
+		ori := rp.HandlerFunc
+		rp.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+			if err := bufferRequestBody(r); err != nil {
+				http.Error(w, "failed to read request body", http.StatusBadRequest)
+				return
+			}
+			ori(w, r)
+		}
+	}
+
 	r := &ReveseProxyRoute{
 		Route: base,
 		rp:    rp,
@@ -91,6 +170,23 @@ func NewReverseProxyRoute(base *Route) (*ReveseProxyRoute, gperr.Error) {
 	return r, nil
 }
 
+// bufferRequestBody reads the whole request body,
+// so it is sent to the upstream with a known length.
+func bufferRequestBody(r *http.Request) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.TransferEncoding = nil
+	return nil
+}
+
 // ReverseProxy implements routes.ReverseProxyRoute.
 func (r *ReveseProxyRoute) ReverseProxy() *reverseproxy.ReverseProxy {
 	return r.rp
@@ -98,7 +194,7 @@ func (r *ReveseProxyRoute) ReverseProxy() *reverseproxy.ReverseProxy {
 
 // Start implements task.TaskStarter.
 func (r *ReveseProxyRoute) Start(parent task.Parent) gperr.Error {
-	if existing, ok := routes.HTTP.Get(r.Key()); ok && !r.UseLoadBalance() {
+	if existing, ok := routes.HTTP.Get(r.Key()); ok && !r.UseLoadBalance() && !r.UsePathRoute() {
 		return gperr.Errorf("route already exists: from provider %s and %s", existing.ProviderName(), r.ProviderName())
 	}
 	r.task = parent.Subtask("http."+r.Name(), false)
@@ -139,9 +235,15 @@ func (r *ReveseProxyRoute) Start(parent task.Parent) gperr.Error {
 		}
 	}
 
-	if r.UseLoadBalance() {
+	switch {
+	case r.UseLoadBalance():
 		r.addToLoadBalancer(parent)
-	} else {
+	case r.UsePathRoute():
+		if err := addToPathRouter(parent, r.task, r, r.Route); err != nil {
+			r.task.Finish(err)
+			return err
+		}
+	default:
 		routes.HTTP.Add(r)
 		r.task.OnFinished("entrypoint_remove_route", func() {
 			routes.HTTP.Del(r)
@@ -201,7 +303,7 @@ func (r *ReveseProxyRoute) addToLoadBalancer(parent task.Parent) {
 	}
 	r.loadBalancer = lb
 
-	server := loadbalance.NewServer(r.task.Name(), r.ProxyURL, r.LoadBalance.Weight, r.handler, r.HealthMon)
+	server := loadbalance.NewServer(r.task.Name(), r.ProxyURL, r.LoadBalance.Weight, r.LoadBalance.Priority, r.loadBalanceZone(), r.LoadBalance.Variant, r.handler, r.HealthMon)
 	lb.AddServer(server)
 	r.task.OnCancel("lb_remove_server", func() {
 		lb.RemoveServer(server)
@@ -14,6 +14,8 @@ import (
 	"github.com/yusing/go-proxy/internal/homepage"
 	idlewatcher "github.com/yusing/go-proxy/internal/idlewatcher/types"
 	netutils "github.com/yusing/go-proxy/internal/net"
+	gphttp "github.com/yusing/go-proxy/internal/net/gphttp"
+	"github.com/yusing/go-proxy/internal/net/proxyproto"
 	net "github.com/yusing/go-proxy/internal/net/types"
 	"github.com/yusing/go-proxy/internal/proxmox"
 	"github.com/yusing/go-proxy/internal/task"
@@ -39,15 +41,22 @@ type (
 		Host   string       `json:"host,omitempty"`
 		Port   route.Port   `json:"port,omitempty"`
 		Root   string       `json:"root,omitempty"`
+		Hosts  []string     `json:"hosts,omitempty"` // extra host names, e.g. `www.example.org` or `*.tenant.example.com`
 
 		route.HTTPConfig
-		PathPatterns []string                       `json:"path_patterns,omitempty"`
-		Rules        rules.Rules                    `json:"rules,omitempty" validate:"omitempty,unique=Name"`
-		HealthCheck  *health.HealthCheckConfig      `json:"healthcheck,omitempty"`
-		LoadBalance  *loadbalance.Config            `json:"load_balance,omitempty"`
-		Middlewares  map[string]docker.LabelMap     `json:"middlewares,omitempty"`
-		Homepage     *homepage.ItemConfig           `json:"homepage,omitempty"`
-		AccessLog    *accesslog.RequestLoggerConfig `json:"access_log,omitempty"`
+		PathPatterns  []string                       `json:"path_patterns,omitempty"`
+		Rules         rules.Rules                    `json:"rules,omitempty" validate:"omitempty,unique=Name"`
+		HealthCheck   *health.HealthCheckConfig      `json:"healthcheck,omitempty"`
+		LoadBalance   *loadbalance.Config            `json:"load_balance,omitempty"`
+		Middlewares   map[string]docker.LabelMap     `json:"middlewares,omitempty"`
+		Homepage      *homepage.ItemConfig           `json:"homepage,omitempty"`
+		AccessLog     *accesslog.RequestLoggerConfig `json:"access_log,omitempty"`
+		ProxyProtocol *route.ProxyProtocolConfig     `json:"proxy_protocol,omitempty"`
+		Redirect      *route.RedirectConfig          `json:"redirect,omitempty"`
+		Static        *route.StaticConfig            `json:"static,omitempty"`
+		PathRoute     *route.PathRouteConfig         `json:"path_route,omitempty"`
+
+		FileServerConfig *route.FileServerConfig `json:"file_server,omitempty"`
 
 		Idlewatcher *idlewatcher.Config `json:"idlewatcher,omitempty"`
 
@@ -70,7 +79,11 @@ type (
 	Routes map[string]*Route
 )
 
-const DefaultHost = "localhost"
+const (
+	DefaultHost = "localhost"
+
+	unixSocketPrefix = "unix://"
+)
 
 func (r Routes) Contains(alias string) bool {
 	_, ok := r[alias]
@@ -160,7 +173,7 @@ func (r *Route) Validate() gperr.Error {
 	case "localhost", "127.0.0.1":
 		switch r.Port.Proxy {
 		case common.ProxyHTTPPort, common.ProxyHTTPSPort, common.APIHTTPPort:
-			if r.Scheme.IsReverseProxy() || r.Scheme == route.SchemeTCP {
+			if r.Scheme.IsReverseProxy() || r.Scheme == route.SchemeTCP || r.Scheme.IsTLSStream() || r.Scheme == route.SchemeTLSPassthrough {
 				return gperr.Errorf("localhost:%d is reserved for godoxy", r.Port.Proxy)
 			}
 		}
@@ -174,16 +187,126 @@ func (r *Route) Validate() gperr.Error {
 	switch r.Scheme {
 	case route.SchemeFileServer:
 		r.ProxyURL = gperr.Collect(errs, net.ParseURL, "file://"+r.Root)
+		if r.FileServerConfig == nil {
+			r.FileServerConfig = new(route.FileServerConfig)
+		}
+		errs.Add(r.FileServerConfig.Validate())
+		r.Host = ""
+		r.Port.Proxy = 0
+	case route.SchemeRedirect:
+		if r.Redirect == nil {
+			errs.Adds("missing redirect config")
+		} else {
+			errs.Add(r.Redirect.Validate())
+			// the target may not be a valid URL before the variables are replaced
+			r.ProxyURL, _ = net.ParseURL(r.Redirect.To)
+		}
+		r.Host = ""
+		r.Port.Proxy = 0
+	case route.SchemeStatic:
+		if r.Static == nil {
+			r.Static = new(route.StaticConfig)
+		}
+		errs.Add(r.Static.Validate())
+		if r.Static.File != "" {
+			r.ProxyURL = gperr.Collect(errs, net.ParseURL, "file://"+r.Static.File)
+		}
 		r.Host = ""
 		r.Port.Proxy = 0
-	case route.SchemeHTTP, route.SchemeHTTPS:
+	case route.SchemeHTTP, route.SchemeHTTPS, route.SchemeH2C, route.SchemeGRPC, route.SchemeGRPCS:
 		if r.Port.Listening != 0 {
 			errs.Addf("unexpected listening port for %s scheme", r.Scheme)
 		}
-		r.ProxyURL = gperr.Collect(errs, net.ParseURL, fmt.Sprintf("%s://%s:%d", r.Scheme, r.Host, r.Port.Proxy))
+		if r.Scheme.HTTPScheme() != r.Scheme && r.IsAgent() {
+			errs.Addf("%s scheme is not supported for agent routes", r.Scheme)
+		}
+		r.ProxyURL = gperr.Collect(errs, net.ParseURL, r.upstreamURL(string(r.Scheme)))
+	case route.SchemeTLSPassthrough:
+		if r.Port.Listening != 0 {
+			errs.Addf("unexpected listening port for %s scheme, it is served on the https port", r.Scheme)
+		}
+		if r.UseLoadBalance() {
+			errs.Addf("load balancing is not supported for %s scheme", r.Scheme)
+		}
+		if r.UseIdleWatcher() {
+			errs.Addf("idlewatcher is not supported for %s scheme", r.Scheme)
+		}
+		r.ProxyURL = gperr.Collect(errs, net.ParseURL, r.upstreamURL(string(r.Scheme)))
 	case route.SchemeTCP, route.SchemeUDP:
 		r.LisURL = gperr.Collect(errs, net.ParseURL, fmt.Sprintf("%s://:%d", r.Scheme, r.Port.Listening))
-		r.ProxyURL = gperr.Collect(errs, net.ParseURL, fmt.Sprintf("%s://%s:%d", r.Scheme, r.Host, r.Port.Proxy))
+		r.ProxyURL = gperr.Collect(errs, net.ParseURL, r.upstreamURL(string(r.Scheme)))
+	case route.SchemeTLS, route.SchemeTCPTLS:
+		r.LisURL = gperr.Collect(errs, net.ParseURL, fmt.Sprintf("%s://:%d", r.Scheme, r.Port.Listening))
+		// the upstream is dialed over TCP, TLS (if any) is on top of it
+		r.ProxyURL = gperr.Collect(errs, net.ParseURL, r.upstreamURL("tcp"))
+	}
+
+	if strings.HasPrefix(r.Host, unixSocketPrefix) {
+		switch {
+		case r.Scheme == route.SchemeUDP:
+			errs.Adds("unix socket is not supported for udp scheme")
+		case r.IsAgent():
+			errs.Adds("unix socket is not supported for agent routes")
+		case !strings.HasPrefix(r.UnixSocket(), "/"):
+			errs.Addf("unix socket path must be absolute, got %q", r.UnixSocket())
+		}
+	}
+
+	if r.Scheme == route.SchemeRedirect || r.Scheme == route.SchemeStatic {
+		if r.Port.Listening != 0 {
+			errs.Addf("unexpected listening port for %s scheme", r.Scheme)
+		}
+		if r.UseLoadBalance() {
+			errs.Addf("load balancing is not supported for %s scheme", r.Scheme)
+		}
+		if r.UseIdleWatcher() {
+			errs.Addf("idlewatcher is not supported for %s scheme", r.Scheme)
+		}
+	}
+
+	if r.HTTPVersion == gphttp.HTTPVersion1 && (r.Scheme == route.SchemeH2C || r.Scheme.IsGRPC()) {
+		errs.Addf("http_version %s is not supported for %s scheme", r.HTTPVersion, r.Scheme)
+	}
+
+	if r.PathRoute != nil {
+		switch {
+		case r.Scheme.IsStream(), r.Scheme == route.SchemeTLSPassthrough:
+			errs.Addf("path_route is not supported for %s scheme", r.Scheme)
+		case r.UseLoadBalance():
+			errs.Adds("path_route cannot be used with load balancing")
+		}
+		errs.Add(r.PathRoute.Validate())
+	}
+
+	if len(r.Hosts) > 0 {
+		if r.Scheme.IsStream() {
+			errs.Addf("hosts is not supported for %s scheme", r.Scheme)
+		}
+		seen := make(map[string]struct{}, len(r.Hosts))
+		for _, host := range r.Hosts {
+			if _, ok := seen[host]; ok {
+				errs.Add(gperr.New("duplicated host").Subject(host))
+				continue
+			}
+			seen[host] = struct{}{}
+			errs.Add(validateHost(host))
+		}
+	}
+
+	if pp := r.ProxyProtocol; pp != nil {
+		switch {
+		case r.Scheme == route.SchemeUDP:
+			errs.Adds("PROXY protocol is not supported for udp scheme")
+		case pp.Accept != nil && !r.Scheme.IsStream():
+			errs.Addf("accepting PROXY protocol is not supported for %s scheme, use entrypoint.proxy_protocol instead", r.Scheme)
+		case pp.Send != 0 && !r.Scheme.HasUpstream():
+			errs.Addf("cannot send PROXY protocol for %s scheme without an upstream", r.Scheme)
+		case pp.Send != 0 && r.Scheme.IsReverseProxy() && r.IsAgent():
+			errs.Adds("sending PROXY protocol is not supported for agent routes")
+		}
+		if pp.Send != 0 && pp.Send != proxyproto.V1 && pp.Send != proxyproto.V2 {
+			errs.Addf("invalid PROXY protocol version %d, expect 1 or 2", pp.Send)
+		}
 	}
 
 	if !r.UseHealthCheck() && (r.UseLoadBalance() || r.UseIdleWatcher()) {
@@ -198,9 +321,15 @@ func (r *Route) Validate() gperr.Error {
 	switch r.Scheme {
 	case route.SchemeFileServer:
 		impl, err = NewFileServer(r)
-	case route.SchemeHTTP, route.SchemeHTTPS:
+	case route.SchemeRedirect:
+		impl, err = NewRedirectRoute(r)
+	case route.SchemeStatic:
+		impl, err = NewStaticRoute(r)
+	case route.SchemeHTTP, route.SchemeHTTPS, route.SchemeH2C, route.SchemeGRPC, route.SchemeGRPCS:
 		impl, err = NewReverseProxyRoute(r)
-	case route.SchemeTCP, route.SchemeUDP:
+	case route.SchemeTLSPassthrough:
+		impl, err = NewTLSPassthroughRoute(r)
+	case route.SchemeTCP, route.SchemeUDP, route.SchemeTLS, route.SchemeTCPTLS:
 		impl, err = NewStreamRoute(r)
 	default:
 		panic(fmt.Errorf("unexpected scheme %s for alias %s", r.Scheme, r.Alias))
@@ -220,7 +349,55 @@ func (r *Route) Start(parent task.Parent) (err gperr.Error) {
 		return gperr.New("route not initialized")
 	}
 
-	return r.impl.Start(parent)
+	if err := r.impl.Start(parent); err != nil {
+		return err
+	}
+	if len(r.Hosts) > 0 {
+		return r.addHosts()
+	}
+	return nil
+}
+
+// addHosts adds the hosts of the route to the host index,
+// pointing to the load balancer or the path router if the route is a part of them.
+func (r *Route) addHosts() gperr.Error {
+	target, ok := r.impl.(routes.HTTPRoute)
+	switch {
+	case r.UseLoadBalance():
+		target, ok = routes.HTTP.Get(r.LoadBalance.Link)
+	case r.UsePathRoute():
+		target, ok = routes.HTTP.Get(r.PathRoute.Link)
+	}
+	if !ok { // should not happen
+		return gperr.Errorf("unexpected route type %T for hosts", r.impl)
+	}
+	if err := routes.AddHosts(target, r.Hosts); err != nil {
+		r.impl.Task().Finish(err)
+		return gperr.Wrap(err)
+	}
+	r.impl.Task().OnCancel("entrypoint_remove_hosts", func() {
+		routes.DelHosts(target, r.Hosts)
+	})
+	return nil
+}
+
+// validateHost validates a host name, or a wildcard host like `*.example.com`.
+func validateHost(host string) gperr.Error {
+	name := strings.TrimPrefix(host, "*.")
+	if name == "" {
+		return gperr.New("invalid host").Subject(host)
+	}
+	for label := range strings.SplitSeq(name, ".") {
+		if label == "" || len(label) > 63 {
+			return gperr.New("invalid host").Subject(host)
+		}
+		for _, c := range label {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' && c != '_' {
+				return gperr.New("invalid host").Subject(host)
+			}
+		}
+	}
+	return nil
 }
 
 func (r *Route) Finish(reason any) {
@@ -265,9 +442,10 @@ func (r *Route) Key() string {
 
 func (r *Route) Type() route.RouteType {
 	switch r.Scheme {
-	case route.SchemeHTTP, route.SchemeHTTPS, route.SchemeFileServer:
+	case route.SchemeHTTP, route.SchemeHTTPS, route.SchemeH2C, route.SchemeGRPC, route.SchemeGRPCS,
+		route.SchemeFileServer, route.SchemeRedirect, route.SchemeStatic, route.SchemeTLSPassthrough:
 		return route.RouteTypeHTTP
-	case route.SchemeTCP, route.SchemeUDP:
+	case route.SchemeTCP, route.SchemeUDP, route.SchemeTLS, route.SchemeTCPTLS:
 		return route.RouteTypeStream
 	}
 	panic(fmt.Errorf("unexpected scheme %s for alias %s", r.Scheme, r.Alias))
@@ -339,7 +517,7 @@ func (r *Route) ShouldExclude() bool {
 		case strings.HasPrefix(r.Container.ContainerName, "buildx_"):
 			return true
 		}
-	} else if r.IsZeroPort() && r.Scheme != route.SchemeFileServer {
+	} else if r.IsZeroPort() && r.Scheme.HasUpstream() {
 		return true
 	}
 	if strings.HasPrefix(r.Alias, "x-") ||
@@ -349,10 +527,45 @@ func (r *Route) ShouldExclude() bool {
 	return false
 }
 
+// UnixSocket returns the path of the upstream unix socket,
+// e.g. `/run/app.sock` for host `unix:///run/app.sock`,
+// or an empty string if the upstream is not a unix socket.
+func (r *Route) UnixSocket() string {
+	path, ok := strings.CutPrefix(r.Host, unixSocketPrefix)
+	if !ok {
+		return ""
+	}
+	return path
+}
+
+// upstreamURL returns the URL of the upstream with the scheme,
+// or the unix socket URL as is.
+func (r *Route) upstreamURL(scheme string) string {
+	if strings.HasPrefix(r.Host, unixSocketPrefix) {
+		return r.Host
+	}
+	return fmt.Sprintf("%s://%s:%d", scheme, r.Host, r.Port.Proxy)
+}
+
 func (r *Route) UseLoadBalance() bool {
 	return r.LoadBalance != nil && r.LoadBalance.Link != ""
 }
 
+// loadBalanceZone returns the zone of the route as a load balancer server.
+func (r *Route) loadBalanceZone() string {
+	if r.LoadBalance.Zone != "" {
+		return r.LoadBalance.Zone
+	}
+	if r.IsAgent() {
+		return r.Agent().Name()
+	}
+	return loadbalance.ZoneLocal
+}
+
+func (r *Route) UsePathRoute() bool {
+	return r.PathRoute != nil && r.PathRoute.Link != ""
+}
+
 func (r *Route) UseIdleWatcher() bool {
 	return r.Idlewatcher != nil && r.Idlewatcher.IdleTimeout > 0
 }
@@ -367,7 +580,14 @@ func (r *Route) UseAccessLog() bool {
 
 func (r *Route) Finalize() {
 	r.Alias = strings.ToLower(strings.TrimSpace(r.Alias))
-	r.Host = strings.ToLower(strings.TrimSpace(r.Host))
+	r.Host = strings.TrimSpace(r.Host)
+	for i, host := range r.Hosts {
+		r.Hosts[i] = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(host), "."))
+	}
+	// socket paths are case sensitive
+	if r.UnixSocket() == "" {
+		r.Host = strings.ToLower(r.Host)
+	}
 
 	isDocker := r.Container != nil
 	cont := r.Container
@@ -414,7 +634,7 @@ func (r *Route) Finalize() {
 			} else {
 				pp = preferredPort(cont.PrivatePortMapping)
 			}
-		case r.Scheme == "https":
+		case r.Scheme == "https", r.Scheme == "grpcs", r.Scheme == "tls-passthrough":
 			pp = 443
 		default:
 			pp = 80
@@ -506,6 +726,13 @@ func (r *Route) FinalizeHomepageConfig() {
 		)
 	}
 
+	// redirect routes have no upstream to find the icon from, use the favicon of the target
+	if hp.Icon == nil && r.Scheme == route.SchemeRedirect && r.ProxyURL != nil &&
+		r.ProxyURL.Host != "" && !strings.Contains(r.ProxyURL.Host, "$") {
+		favicon := r.ProxyURL.Scheme + "://" + r.ProxyURL.Host + "/favicon.ico"
+		hp.Icon = &homepage.IconURL{FullURL: &favicon, IconSource: homepage.IconSourceAbsolute}
+	}
+
 	if hp.Category == "" {
 		if config.GetInstance().Value().Homepage.UseDefaultCategories {
 			for _, ref := range refs {
@@ -520,6 +747,8 @@ func (r *Route) FinalizeHomepageConfig() {
 			switch {
 			case r.UseLoadBalance():
 				hp.Category = "Load-balanced"
+			case r.Scheme == route.SchemeRedirect:
+				hp.Category = "Redirects"
 			case isDocker:
 				hp.Category = "Docker"
 			default:
@@ -7,6 +7,7 @@ import (
 	"github.com/yusing/go-proxy/internal/common"
 	"github.com/yusing/go-proxy/internal/docker"
 	loadbalance "github.com/yusing/go-proxy/internal/net/gphttp/loadbalancer/types"
+	"github.com/yusing/go-proxy/internal/net/proxyproto"
 	route "github.com/yusing/go-proxy/internal/route/types"
 	expect "github.com/yusing/go-proxy/internal/utils/testing"
 	"github.com/yusing/go-proxy/internal/watcher/health"
@@ -92,6 +93,212 @@ func TestRouteValidate(t *testing.T) {
 		expect.NotNil(t, r.impl, "Impl should be initialized")
 	})
 
+	t.Run("TLSPassthroughScheme", func(t *testing.T) {
+		r := &Route{
+			Alias:  "test",
+			Scheme: route.SchemeTLSPassthrough,
+			Host:   "example.com",
+		}
+		err := r.Validate()
+		expect.NoError(t, err, "Validate should not return error for valid TLS passthrough route")
+		expect.NotNil(t, r.impl, "Impl should be initialized")
+		expect.Equal(t, r.ProxyURL.Host, "example.com:443")
+	})
+
+	t.Run("ListeningPortWithTLSPassthrough", func(t *testing.T) {
+		r := &Route{
+			Alias:  "test",
+			Scheme: route.SchemeTLSPassthrough,
+			Host:   "example.com",
+			Port:   route.Port{Proxy: 443, Listening: 8443},
+		}
+		err := r.Validate()
+		expect.HasError(t, err, "Validate should return error for TLS passthrough scheme with listening port")
+		expect.ErrorContains(t, err, "unexpected listening port")
+	})
+
+	t.Run("TLSStreamScheme", func(t *testing.T) {
+		r := &Route{
+			Alias:  "test",
+			Scheme: route.SchemeTCPTLS,
+			Host:   "example.com",
+			Port:   route.Port{Proxy: 1883, Listening: 8883},
+		}
+		err := r.Validate()
+		expect.NoError(t, err, "Validate should not return error for valid TLS stream route")
+		expect.NotNil(t, r.impl, "Impl should be initialized")
+		expect.Equal(t, r.Type(), route.RouteTypeStream)
+		expect.Equal(t, r.ProxyURL.Scheme, "tcp")
+	})
+
+	t.Run("ProxyProtocolWithUDP", func(t *testing.T) {
+		r := &Route{
+			Alias:         "test",
+			Scheme:        route.SchemeUDP,
+			Host:          "example.com",
+			Port:          route.Port{Proxy: 53, Listening: 5353},
+			ProxyProtocol: &route.ProxyProtocolConfig{Send: proxyproto.V2},
+		}
+		err := r.Validate()
+		expect.HasError(t, err, "Validate should return error for PROXY protocol with UDP scheme")
+		expect.ErrorContains(t, err, "not supported for udp")
+	})
+
+	t.Run("UnixSocket", func(t *testing.T) {
+		r := &Route{
+			Alias:  "test",
+			Scheme: route.SchemeHTTP,
+			Host:   "unix:///run/App.sock",
+		}
+		err := r.Validate()
+		expect.NoError(t, err, "Validate should not return error for valid unix socket route")
+		expect.NotNil(t, r.impl, "Impl should be initialized")
+		expect.Equal(t, r.UnixSocket(), "/run/App.sock")
+		expect.Equal(t, r.ProxyURL.Scheme, "unix")
+		expect.Equal(t, r.ProxyURL.Path, "/run/App.sock")
+	})
+
+	t.Run("UnixSocketWithUDP", func(t *testing.T) {
+		r := &Route{
+			Alias:  "test",
+			Scheme: route.SchemeUDP,
+			Host:   "unix:///run/app.sock",
+			Port:   route.Port{Listening: 5353},
+		}
+		err := r.Validate()
+		expect.HasError(t, err, "Validate should return error for unix socket with UDP scheme")
+		expect.ErrorContains(t, err, "not supported for udp")
+	})
+
+	t.Run("RedirectScheme", func(t *testing.T) {
+		r := &Route{
+			Alias:    "test",
+			Scheme:   route.SchemeRedirect,
+			Redirect: &route.RedirectConfig{To: "https://new.example.com"},
+		}
+		err := r.Validate()
+		expect.NoError(t, err, "Validate should not return error for valid redirect route")
+		expect.NotNil(t, r.impl, "Impl should be initialized")
+		expect.Equal(t, r.Type(), route.RouteTypeHTTP)
+		expect.Equal(t, r.Redirect.Code, route.RedirectCodeDefault)
+	})
+
+	t.Run("RedirectSchemeWithoutConfig", func(t *testing.T) {
+		r := &Route{
+			Alias:  "test",
+			Scheme: route.SchemeRedirect,
+		}
+		err := r.Validate()
+		expect.HasError(t, err, "Validate should return error for redirect route without config")
+		expect.ErrorContains(t, err, "missing redirect config")
+	})
+
+	t.Run("StaticScheme", func(t *testing.T) {
+		r := &Route{
+			Alias:  "test",
+			Scheme: route.SchemeStatic,
+			Static: &route.StaticConfig{StatusCode: 204},
+		}
+		err := r.Validate()
+		expect.NoError(t, err, "Validate should not return error for valid static route")
+		expect.NotNil(t, r.impl, "Impl should be initialized")
+		expect.False(t, r.ShouldExclude())
+	})
+
+	t.Run("Hosts", func(t *testing.T) {
+		r := &Route{
+			Alias:  "test",
+			Scheme: route.SchemeHTTP,
+			Host:   "example.com",
+			Port:   route.Port{Proxy: 80},
+			Hosts:  []string{"WWW.example.org.", "*.tenant.example.com"},
+		}
+		err := r.Validate()
+		expect.NoError(t, err, "Validate should not return error for valid hosts")
+		expect.Equal(t, r.Hosts, []string{"www.example.org", "*.tenant.example.com"})
+	})
+
+	t.Run("InvalidHosts", func(t *testing.T) {
+		r := &Route{
+			Alias:  "test",
+			Scheme: route.SchemeHTTP,
+			Host:   "example.com",
+			Port:   route.Port{Proxy: 80},
+			Hosts:  []string{"a.*.example.com", "a..example.com", "app.example.com", "app.example.com"},
+		}
+		err := r.Validate()
+		expect.HasError(t, err, "Validate should return error for invalid hosts")
+		expect.ErrorContains(t, err, "a.*.example.com")
+		expect.ErrorContains(t, err, "a..example.com")
+		expect.ErrorContains(t, err, "duplicated host")
+	})
+
+	t.Run("HostsWithTCP", func(t *testing.T) {
+		r := &Route{
+			Alias:  "test",
+			Scheme: route.SchemeTCP,
+			Host:   "example.com",
+			Port:   route.Port{Proxy: 80, Listening: 8080},
+			Hosts:  []string{"app.example.com"},
+		}
+		err := r.Validate()
+		expect.HasError(t, err, "Validate should return error for hosts with TCP scheme")
+		expect.ErrorContains(t, err, "hosts is not supported")
+	})
+
+	t.Run("PathRoute", func(t *testing.T) {
+		r := &Route{
+			Alias:     "api",
+			Scheme:    route.SchemeHTTP,
+			Host:      "example.com",
+			Port:      route.Port{Proxy: 80},
+			PathRoute: &route.PathRouteConfig{Link: "app", Prefix: "/api/"},
+		}
+		err := r.Validate()
+		expect.NoError(t, err)
+		expect.True(t, r.UsePathRoute())
+		expect.Equal(t, r.PathRoute.Prefix, "/api")
+	})
+
+	t.Run("PathRouteWithoutLeadingSlash", func(t *testing.T) {
+		r := &Route{
+			Alias:     "api",
+			Scheme:    route.SchemeHTTP,
+			Host:      "example.com",
+			Port:      route.Port{Proxy: 80},
+			PathRoute: &route.PathRouteConfig{Link: "app", Prefix: "api"},
+		}
+		err := r.Validate()
+		expect.HasError(t, err, "Validate should return error for path prefix without leading slash")
+		expect.ErrorContains(t, err, "path prefix must start with /")
+	})
+
+	t.Run("PathRouteWithTCP", func(t *testing.T) {
+		r := &Route{
+			Alias:     "test",
+			Scheme:    route.SchemeTCP,
+			Host:      "example.com",
+			Port:      route.Port{Proxy: 80, Listening: 8080},
+			PathRoute: &route.PathRouteConfig{Link: "app", Prefix: "/api"},
+		}
+		err := r.Validate()
+		expect.HasError(t, err, "Validate should return error for path_route with TCP scheme")
+		expect.ErrorContains(t, err, "path_route is not supported")
+	})
+
+	t.Run("HTTPVersionWithGRPC", func(t *testing.T) {
+		r := &Route{
+			Alias:      "test",
+			Scheme:     route.SchemeGRPC,
+			Host:       "example.com",
+			Port:       route.Port{Proxy: 50051},
+			HTTPConfig: route.HTTPConfig{HTTPVersion: "1.1"},
+		}
+		err := r.Validate()
+		expect.HasError(t, err, "Validate should return error for http_version 1.1 with grpc scheme")
+		expect.ErrorContains(t, err, "http_version 1.1 is not supported")
+	})
+
 	t.Run("DockerContainer", func(t *testing.T) {
 		r := &Route{
 			Alias:  "test",
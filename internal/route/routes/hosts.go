@@ -0,0 +1,191 @@
+package routes
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/yusing/go-proxy/internal/utils/strutils"
+	"github.com/yusing/go-proxy/internal/utils/trie"
+)
+
+// hostEntry is the value of a host in the host index.
+//
+// Entries are replaced instead of modified, so lookups are lock free.
+// A removed host is kept as an entry without route,
+// since values of the trie cannot be deleted.
+type hostEntry struct {
+	route HTTPRoute
+	// refs is the number of routes referencing the host,
+	// e.g. servers of a load balanced route.
+	refs int
+}
+
+var (
+	// hostIndex indexes hosts by their labels in reverse order,
+	// e.g. `app.example.com` is stored at `com.example.app`,
+	// so hosts under the same domain share the same prefix.
+	//
+	// It is replaced as a whole on clear, while lookups are in progress.
+	hostIndex atomic.Pointer[trie.Root]
+	hostMu    sync.Mutex
+
+	// matchDomains are the match domains of the entrypoint, with leading dots,
+	// to find the routes an exact host would shadow, see AddHosts.
+	matchDomains atomic.Pointer[[]string]
+)
+
+func init() {
+	hostIndex.Store(trie.NewTrie())
+}
+
+const (
+	wildcardLabel = "*"
+	// wildcardSegment is the key segment of the leading wildcard label,
+	// since keys with wildcard cannot be stored in the trie.
+	//
+	// `#` is invalid in both route hosts and Host headers, so it never collides with a label, e.g. `__`.
+	wildcardSegment = "#"
+)
+
+// hostKey returns the key of the host in the host index.
+func hostKey(host string) *trie.Key {
+	labels := strutils.SplitRune(host, '.')
+	slices.Reverse(labels)
+	if labels[len(labels)-1] == wildcardLabel {
+		return trie.NewKey(strings.Join(labels[:len(labels)-1], ".")).With(wildcardSegment)
+	}
+	return trie.NewKey(strings.Join(labels, "."))
+}
+
+func loadHost(key *trie.Key) *hostEntry {
+	v, ok := hostIndex.Load().Get(key)
+	if !ok {
+		return nil
+	}
+	return v.(*hostEntry)
+}
+
+// SetMatchDomains sets the match domains of the entrypoint.
+func SetMatchDomains(domains []string) {
+	matchDomains.Store(&domains)
+}
+
+// AddHosts adds hosts of the route to the host index.
+//
+// It returns an error without adding any host if a host is used by another route,
+// or resolves to another route by alias, since hosts are looked up first.
+func AddHosts(r HTTPRoute, hosts []string) error {
+	hostMu.Lock()
+	defer hostMu.Unlock()
+
+	for _, host := range hosts {
+		if e := loadHost(hostKey(host)); e != nil && e.route != nil && e.route != r {
+			return fmt.Errorf("host %s already exists: from route %s of provider %s and %s", host, e.route.Name(), e.route.ProviderName(), r.ProviderName())
+		}
+		if strings.HasPrefix(host, wildcardLabel+".") { // wildcards are looked up after aliases
+			continue
+		}
+		for _, alias := range aliasesOf(host) {
+			if existing, ok := HTTP.Get(alias); ok && existing != r {
+				return fmt.Errorf("host %s already exists: alias of route %s of provider %s and %s", host, existing.Name(), existing.ProviderName(), r.ProviderName())
+			}
+		}
+	}
+	for _, host := range hosts {
+		key := hostKey(host)
+		refs := 1
+		if e := loadHost(key); e != nil && e.route != nil {
+			refs = e.refs + 1
+		}
+		hostIndex.Load().Store(key, &hostEntry{route: r, refs: refs})
+	}
+	return nil
+}
+
+// DelHosts removes hosts of the route from the host index.
+func DelHosts(r HTTPRoute, hosts []string) {
+	hostMu.Lock()
+	defer hostMu.Unlock()
+
+	for _, host := range hosts {
+		key := hostKey(host)
+		e := loadHost(key)
+		if e == nil || e.route != r {
+			continue
+		}
+		if e.refs > 1 {
+			hostIndex.Load().Store(key, &hostEntry{route: r, refs: e.refs - 1})
+		} else {
+			hostIndex.Load().Store(key, &hostEntry{})
+		}
+	}
+}
+
+func clearHosts() {
+	hostMu.Lock()
+	defer hostMu.Unlock()
+	hostIndex.Store(trie.NewTrie())
+}
+
+// GetHTTPRouteByHost returns the route with the exact host in its hosts.
+func GetHTTPRouteByHost(host string) (HTTPRoute, bool) {
+	host = hostname(host)
+	if host == "" {
+		return nil, false
+	}
+	if e := loadHost(hostKey(host)); e != nil && e.route != nil {
+		return e.route, true
+	}
+	return nil, false
+}
+
+// GetHTTPRouteByWildcard returns the route with the most specific wildcard host matching the host,
+// e.g. `*.tenant.example.com` over `*.example.com` for `app.tenant.example.com`.
+//
+// A wildcard matches one or more labels.
+func GetHTTPRouteByWildcard(host string) (HTTPRoute, bool) {
+	host = hostname(host)
+	if host == "" {
+		return nil, false
+	}
+	labels := strutils.SplitRune(host, '.')
+	slices.Reverse(labels)
+	for i := len(labels) - 1; i > 0; i-- {
+		key := trie.NewKey(strings.Join(labels[:i], ".")).With(wildcardSegment)
+		if e := loadHost(key); e != nil && e.route != nil {
+			return e.route, true
+		}
+	}
+	return nil, false
+}
+
+// aliasesOf returns the aliases the entrypoint resolves the host to, if no route has it in hosts.
+func aliasesOf(host string) []string {
+	var domains []string
+	if p := matchDomains.Load(); p != nil {
+		domains = *p
+	}
+	if len(domains) == 0 {
+		alias, _, _ := strings.Cut(host, ".")
+		return []string{alias, host}
+	}
+	aliases := make([]string, 0, 2)
+	for _, domain := range domains {
+		if alias, ok := strings.CutSuffix(host, domain); ok {
+			aliases = append(aliases, alias)
+		}
+	}
+	return append(aliases, host)
+}
+
+// hostname returns the lowercase host name without port.
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"io"
 	"net/http"
 
 	"github.com/yusing/go-proxy/agent/pkg/agent"
@@ -53,6 +54,12 @@ type (
 		HTTPRoute
 		ReverseProxy() *reverseproxy.ReverseProxy
 	}
+	// TLSPassthroughRoute is a HTTP route that receives the raw TLS connections
+	// of its host name on the HTTPS port.
+	TLSPassthroughRoute interface {
+		HTTPRoute
+		HandleTLSConn(conn io.ReadWriteCloser) error
+	}
 	StreamRoute interface {
 		Route
 		net.Stream
@@ -42,6 +42,7 @@ func NumRoutes() int {
 func Clear() {
 	HTTP.Clear()
 	Stream.Clear()
+	clearHosts()
 }
 
 func GetHTTPRouteOrExact(alias, host string) (HTTPRoute, bool) {
@@ -0,0 +1,216 @@
+package route
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/yusing/go-proxy/internal/gperr"
+	"github.com/yusing/go-proxy/internal/logging/accesslog"
+	gphttp "github.com/yusing/go-proxy/internal/net/gphttp"
+	"github.com/yusing/go-proxy/internal/net/gphttp/middleware"
+	"github.com/yusing/go-proxy/internal/route/routes"
+	route "github.com/yusing/go-proxy/internal/route/types"
+	"github.com/yusing/go-proxy/internal/task"
+	"github.com/yusing/go-proxy/internal/watcher/health"
+	"github.com/yusing/go-proxy/internal/watcher/health/monitor"
+)
+
+type (
+	// StaticRoute is a HTTP route responded by GoDoxy itself without an upstream,
+	// i.e. routes of the redirect and static schemes.
+	StaticRoute struct {
+		*Route
+
+		Health *monitor.FileServerHealthMonitor `json:"health,omitempty"`
+
+		task         *task.Task
+		middleware   *middleware.Middleware
+		handler      http.Handler
+		accessLogger *accesslog.AccessLogger
+	}
+)
+
+func NewRedirectRoute(base *Route) (*StaticRoute, gperr.Error) {
+	return newStaticRoute(base, redirectHandler(base.Redirect))
+}
+
+func NewStaticRoute(base *Route) (*StaticRoute, gperr.Error) {
+	return newStaticRoute(base, staticHandler(base.Static))
+}
+
+func newStaticRoute(base *Route, handler http.Handler) (*StaticRoute, gperr.Error) {
+	s := &StaticRoute{Route: base, handler: handler}
+
+	if len(s.Middlewares) > 0 {
+		mid, err := middleware.BuildMiddlewareFromMap(s.Alias, s.Middlewares)
+		if err != nil {
+			return nil, err
+		}
+		s.middleware = mid
+	}
+
+	return s, nil
+}
+
+func redirectHandler(cfg *route.RedirectConfig) http.Handler {
+	code := cfg.Code
+	if code == 0 {
+		code = route.RedirectCodeDefault
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := middleware.ReplaceRequestVars(r, cfg.To)
+		if cfg.PreservePath {
+			target = strings.TrimSuffix(target, "/") + r.URL.RequestURI()
+		}
+		http.Redirect(w, r, target, code)
+	})
+}
+
+func staticHandler(cfg *route.StaticConfig) http.Handler {
+	code := cfg.StatusCode
+	if code == 0 {
+		code = http.StatusOK
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		for k, v := range cfg.Headers {
+			h.Set(k, v)
+		}
+		if cfg.File != "" {
+			serveStaticFile(w, r, cfg.File, code)
+			return
+		}
+		if cfg.Body != "" {
+			if h.Get("Content-Type") == "" {
+				h.Set("Content-Type", http.DetectContentType([]byte(cfg.Body)))
+			}
+			h.Set("Content-Length", strconv.Itoa(len(cfg.Body)))
+		}
+		w.WriteHeader(code)
+		if r.Method != http.MethodHead {
+			_, _ = io.WriteString(w, cfg.Body)
+		}
+	})
+}
+
+func serveStaticFile(w http.ResponseWriter, r *http.Request, path string, code int) {
+	f, err := os.Open(path)
+	if err != nil {
+		http.Error(w, "404 page not found", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil || stat.IsDir() {
+		http.Error(w, "404 page not found", http.StatusNotFound)
+		return
+	}
+
+	// conditional and range requests only make sense for 200 responses
+	if code == http.StatusOK {
+		http.ServeContent(w, r, stat.Name(), stat.ModTime(), f)
+		return
+	}
+
+	h := w.Header()
+	if h.Get("Content-Type") == "" {
+		if ct := mime.TypeByExtension(filepath.Ext(path)); ct != "" {
+			h.Set("Content-Type", ct)
+		}
+	}
+	h.Set("Content-Length", strconv.FormatInt(stat.Size(), 10))
+	w.WriteHeader(code)
+	if r.Method != http.MethodHead {
+		_, _ = io.Copy(w, f)
+	}
+}
+
+// Start implements task.TaskStarter.
+func (s *StaticRoute) Start(parent task.Parent) gperr.Error {
+	if existing, ok := routes.HTTP.Get(s.Key()); ok && !s.UsePathRoute() {
+		return gperr.Errorf("route already exists: from provider %s and %s", existing.ProviderName(), s.ProviderName())
+	}
+	s.task = parent.Subtask(string(s.Scheme)+"."+s.Name(), false)
+
+	pathPatterns := s.PathPatterns
+	switch {
+	case len(pathPatterns) == 0:
+	case len(pathPatterns) == 1 && pathPatterns[0] == "/":
+	default:
+		mux := gphttp.NewServeMux()
+		patErrs := gperr.NewBuilder("invalid path pattern(s)")
+		for _, p := range pathPatterns {
+			patErrs.Add(mux.Handle(p, s.handler))
+		}
+		if err := patErrs.Error(); err != nil {
+			s.task.Finish(err)
+			return err
+		}
+		s.handler = mux
+	}
+
+	if s.middleware != nil {
+		next := s.handler
+		s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s.middleware.ServeHTTP(next.ServeHTTP, w, r)
+		})
+	}
+
+	if s.UseAccessLog() {
+		var err error
+		s.accessLogger, err = accesslog.NewAccessLogger(s.task, s.AccessLog)
+		if err != nil {
+			s.task.Finish(err)
+			return gperr.Wrap(err)
+		}
+	}
+
+	if s.UseHealthCheck() && s.Static != nil && s.Static.File != "" {
+		s.Health = monitor.NewFileServerHealthMonitor(s.HealthCheck, s.Static.File)
+		if err := s.Health.Start(s.task); err != nil {
+			return err
+		}
+	}
+
+	if s.UsePathRoute() {
+		if err := addToPathRouter(parent, s.task, s, s.Route); err != nil {
+			s.task.Finish(err)
+			return err
+		}
+		return nil
+	}
+
+	routes.HTTP.Add(s)
+	s.task.OnCancel("entrypoint_remove_route", func() {
+		routes.HTTP.Del(s)
+	})
+	return nil
+}
+
+// Task implements task.TaskStarter.
+func (s *StaticRoute) Task() *task.Task {
+	return s.task
+}
+
+// Finish implements task.TaskFinisher.
+func (s *StaticRoute) Finish(reason any) {
+	s.task.Finish(reason)
+}
+
+// ServeHTTP implements http.Handler.
+func (s *StaticRoute) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	serveWithAccessLog(s.handler, s.accessLogger, w, req)
+}
+
+func (s *StaticRoute) HealthMonitor() health.HealthMonitor {
+	if s.Health == nil {
+		return nil
+	}
+	return s.Health
+}
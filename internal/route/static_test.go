@@ -0,0 +1,105 @@
+package route
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yusing/go-proxy/internal/logging/accesslog"
+	route "github.com/yusing/go-proxy/internal/route/types"
+	"github.com/yusing/go-proxy/internal/task"
+	expect "github.com/yusing/go-proxy/internal/utils/testing"
+)
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    *route.RedirectConfig
+		target string
+		want   string
+		code   int
+	}{
+		{
+			name:   "default code",
+			cfg:    &route.RedirectConfig{To: "https://new.example.com"},
+			target: "http://old.example.com/a/b?c=d",
+			want:   "https://new.example.com",
+			code:   http.StatusPermanentRedirect,
+		},
+		{
+			name:   "preserve path",
+			cfg:    &route.RedirectConfig{To: "https://new.example.com/", Code: http.StatusFound, PreservePath: true},
+			target: "http://old.example.com/a/b?c=d",
+			want:   "https://new.example.com/a/b?c=d",
+			code:   http.StatusFound,
+		},
+		{
+			name:   "template",
+			cfg:    &route.RedirectConfig{To: "https://new.example.com/$req_host$req_path", Code: http.StatusMovedPermanently},
+			target: "http://old.example.com/a/b?c=d",
+			want:   "https://new.example.com/old.example.com/a/b",
+			code:   http.StatusMovedPermanently,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			redirectHandler(tt.cfg).ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			expect.Equal(t, w.Code, tt.code)
+			expect.Equal(t, w.Header().Get("Location"), tt.want)
+		})
+	}
+}
+
+func TestStaticHandler(t *testing.T) {
+	t.Run("NoContent", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		staticHandler(&route.StaticConfig{StatusCode: http.StatusNoContent}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/generate_204", nil))
+		expect.Equal(t, w.Code, http.StatusNoContent)
+		expect.Equal(t, w.Body.Len(), 0)
+	})
+
+	t.Run("Body", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		staticHandler(&route.StaticConfig{
+			Headers: map[string]string{"Cache-Control": "no-store"},
+			Body:    "Success",
+		}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		expect.Equal(t, w.Code, http.StatusOK)
+		expect.Equal(t, w.Body.String(), "Success")
+		expect.Equal(t, w.Header().Get("Cache-Control"), "no-store")
+		expect.Equal(t, w.Header().Get("Content-Type"), "text/plain; charset=utf-8")
+	})
+
+	t.Run("File", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "maintenance.html")
+		expect.NoError(t, os.WriteFile(file, []byte("<html>maintenance</html>"), 0o644))
+
+		w := httptest.NewRecorder()
+		staticHandler(&route.StaticConfig{
+			StatusCode: http.StatusServiceUnavailable,
+			File:       file,
+		}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		expect.Equal(t, w.Code, http.StatusServiceUnavailable)
+		expect.Equal(t, w.Body.String(), "<html>maintenance</html>")
+		expect.Equal(t, w.Header().Get("Content-Type"), "text/html; charset=utf-8")
+	})
+}
+
+func TestServeWithAccessLog(t *testing.T) {
+	parent := task.RootTask("test", false)
+	defer parent.Finish(nil)
+	file := accesslog.NewMockFile()
+	logger := accesslog.NewAccessLoggerWithIO(parent, file, accesslog.DefaultRequestLoggerConfig())
+
+	w := httptest.NewRecorder()
+	handler := redirectHandler(&route.RedirectConfig{To: "https://new.example.com"})
+	serveWithAccessLog(handler, logger, w, httptest.NewRequest(http.MethodGet, "http://old.example.com/", nil))
+	logger.Flush()
+
+	expect.Equal(t, w.Code, http.StatusPermanentRedirect)
+	expect.True(t, strings.Contains(string(file.Content()), `"GET / HTTP/1.1" 308 `))
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/yusing/go-proxy/internal/gperr"
 	"github.com/yusing/go-proxy/internal/idlewatcher"
+	"github.com/yusing/go-proxy/internal/net/gphttp/loadbalancer"
+	loadbalance "github.com/yusing/go-proxy/internal/net/gphttp/loadbalancer/types"
 	net "github.com/yusing/go-proxy/internal/net/types"
 	"github.com/yusing/go-proxy/internal/route/routes"
 	"github.com/yusing/go-proxy/internal/task"
@@ -15,7 +17,6 @@ import (
 	"github.com/yusing/go-proxy/internal/watcher/health/monitor"
 )
 
-// TODO: support stream load balance.
 type StreamRoute struct {
 	*Route
 
@@ -23,6 +24,8 @@ type StreamRoute struct {
 
 	HealthMon health.HealthMonitor `json:"health"`
 
+	loadBalancer *loadbalancer.LoadBalancer
+
 	task *task.Task
 
 	l zerolog.Logger
@@ -41,7 +44,7 @@ func NewStreamRoute(base *Route) (routes.Route, gperr.Error) {
 
 // Start implements task.TaskStarter.
 func (r *StreamRoute) Start(parent task.Parent) gperr.Error {
-	if existing, ok := routes.Stream.Get(r.Key()); ok {
+	if existing, ok := routes.Stream.Get(r.Key()); ok && !r.UseLoadBalance() {
 		return gperr.Errorf("route already exists: from provider %s and %s", existing.ProviderName(), r.ProviderName())
 	}
 	r.task = parent.Subtask("stream."+r.Name(), true)
@@ -60,19 +63,28 @@ func (r *StreamRoute) Start(parent task.Parent) gperr.Error {
 		r.HealthMon = monitor.NewMonitor(r)
 	}
 
-	if err := r.Setup(); err != nil {
-		r.task.Finish(err)
-		return gperr.Wrap(err)
+	if !r.UseLoadBalance() {
+		if err := r.Setup(); err != nil {
+			r.task.Finish(err)
+			return gperr.Wrap(err)
+		}
+		r.l.Info().Int("port", r.Port.Listening).Msg("listening")
 	}
 
-	r.l.Info().Int("port", r.Port.Listening).Msg("listening")
-
 	if r.HealthMon != nil {
 		if err := r.HealthMon.Start(r.task); err != nil {
 			gperr.LogWarn("health monitor error", err, &r.l)
 		}
 	}
 
+	if r.UseLoadBalance() {
+		if err := r.addToLoadBalancer(parent); err != nil {
+			r.task.Finish(err)
+			return err
+		}
+		return nil
+	}
+
 	go r.acceptConnections()
 
 	routes.Stream.Add(r)
@@ -96,6 +108,75 @@ func (r *StreamRoute) HealthMonitor() health.HealthMonitor {
 	return r.HealthMon
 }
 
+// addToLoadBalancer adds the route as a server of the load balancer named by `load_balance.link`.
+//
+// The load balancer listens on the listening port of the first route,
+// other routes must have the same scheme and listening port (or zero).
+func (r *StreamRoute) addToLoadBalancer(parent task.Parent) gperr.Error {
+	var lb *loadbalancer.LoadBalancer
+	cfg := r.LoadBalance
+	l, ok := routes.Stream.Get(cfg.Link)
+	if ok {
+		linked, ok := l.(*StreamRoute)
+		if !ok || linked.loadBalancer == nil {
+			return gperr.Errorf("route %s already exists and is not a load balancer", cfg.Link)
+		}
+		if linked.Scheme != r.Scheme {
+			return gperr.Errorf("scheme mismatch with load balancer %s: %s != %s", cfg.Link, r.Scheme, linked.Scheme)
+		}
+		if r.Port.Listening != 0 && r.Port.Listening != linked.Port.Listening {
+			return gperr.Errorf("listening port mismatch with load balancer %s: %d != %d", cfg.Link, r.Port.Listening, linked.Port.Listening)
+		}
+		lb = linked.loadBalancer
+		lb.UpdateConfigIfNeeded(cfg)
+	} else {
+		lb = loadbalancer.New(cfg)
+		_ = lb.Start(parent) // always return nil
+		linked := &StreamRoute{
+			Route: &Route{
+				Alias:    cfg.Link,
+				Scheme:   r.Scheme,
+				Port:     r.Port,
+				Homepage: r.Homepage,
+				// the load balancer handles TLS and PROXY protocol for all its servers
+				HTTPConfig:    r.HTTPConfig,
+				ProxyProtocol: r.ProxyProtocol,
+				Metadata: Metadata{
+					LisURL: r.LisURL,
+				},
+			},
+			HealthMon:    lb,
+			loadBalancer: lb,
+			task:         lb.Task(),
+			l: log.With().
+				Str("type", string(r.Scheme)).
+				Str("name", cfg.Link).
+				Logger(),
+		}
+		linked.Stream = NewStream(linked)
+		if err := linked.Setup(); err != nil {
+			lb.Finish(err)
+			return gperr.Wrap(err)
+		}
+		linked.l.Info().Int("port", linked.Port.Listening).Msg("listening")
+
+		go linked.acceptConnections()
+
+		routes.Stream.Add(linked)
+		lb.Task().OnFinished("entrypoint_remove_route", func() {
+			routes.Stream.Del(linked)
+		})
+	}
+	r.loadBalancer = lb
+
+	server := loadbalance.NewServer(r.task.Name(), r.ProxyURL, r.LoadBalance.Weight, r.LoadBalance.Priority, r.loadBalanceZone(), r.LoadBalance.Variant, nil, r.HealthMon)
+	lb.AddServer(server)
+	r.task.OnCancel("lb_remove_server", func() {
+		lb.RemoveServer(server)
+	})
+	return nil
+}
+
 func (r *StreamRoute) acceptConnections() {
 	defer r.task.Finish("listener closed")
 
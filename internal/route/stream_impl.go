@@ -1,12 +1,16 @@
 package route
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"time"
 
+	config "github.com/yusing/go-proxy/internal/config/types"
+	"github.com/yusing/go-proxy/internal/net/proxyproto"
 	"github.com/yusing/go-proxy/internal/net/types"
 	U "github.com/yusing/go-proxy/internal/utils"
 )
@@ -23,6 +27,7 @@ type (
 const (
 	streamFirstConnBufferSize = 128
 	streamDialTimeout         = 5 * time.Second
+	streamTLSHandshakeTimeout = 10 * time.Second
 )
 
 func NewStream(base *StreamRoute) *Stream {
@@ -45,10 +50,12 @@ func (stream *Stream) Setup() error {
 	ctx := stream.task.Context()
 
 	switch stream.Scheme {
-	case "tcp":
-		stream.targetAddr, err = net.ResolveTCPAddr("tcp", stream.ProxyURL.Host)
-		if err != nil {
-			return err
+	case "tcp", "tls", "tcp+tls":
+		if stream.loadBalancer == nil {
+			stream.targetAddr, err = resolveUpstreamAddr(stream.ProxyURL)
+			if err != nil {
+				return err
+			}
 		}
 		tcpListener, err := lcfg.Listen(ctx, "tcp", stream.LisURL.Host)
 		if err != nil {
@@ -56,11 +63,24 @@ func (stream *Stream) Setup() error {
 		}
 		// in case ListeningPort was zero, get the actual port
 		stream.Port.Listening = tcpListener.Addr().(*net.TCPAddr).Port
+		if pp := stream.ProxyProtocol; pp != nil {
+			tcpListener = pp.Accept.WrapListener(tcpListener, &stream.l)
+		}
+		if stream.Scheme.IsTLSStream() {
+			tlsConfig, err := stream.listenerTLSConfig()
+			if err != nil {
+				tcpListener.Close()
+				return err
+			}
+			tcpListener = tls.NewListener(tcpListener, tlsConfig)
+		}
 		stream.listener = types.NetListener(tcpListener)
 	case "udp":
-		stream.targetAddr, err = net.ResolveUDPAddr("udp", stream.ProxyURL.Host)
-		if err != nil {
-			return err
+		if stream.loadBalancer == nil {
+			stream.targetAddr, err = net.ResolveUDPAddr("udp", stream.ProxyURL.Host)
+			if err != nil {
+				return err
+			}
 		}
 		udpListener, err := lcfg.ListenPacket(ctx, "udp", stream.LisURL.Host)
 		if err != nil {
@@ -72,7 +92,7 @@ func (stream *Stream) Setup() error {
 			return errors.New("udp listener is not *net.UDPConn")
 		}
 		stream.Port.Listening = udpConn.LocalAddr().(*net.UDPAddr).Port
-		stream.listener = NewUDPForwarder(ctx, udpConn, stream.targetAddr)
+		stream.listener = NewUDPForwarder(ctx, udpConn, stream.dstAddr)
 	default:
 		panic("should not reach here")
 	}
@@ -110,13 +130,27 @@ func (stream *Stream) Handle(conn types.StreamConn) error {
 			return fmt.Errorf("unexpected listener type: %T", stream)
 		}
 	case io.ReadWriteCloser:
-		dialer := &net.Dialer{Timeout: streamDialTimeout}
-		dstConn, err := dialer.DialContext(stream.task.Context(), stream.targetAddr.Network(), stream.targetAddr.String())
+		defer conn.Close()
+		if tlsConn, ok := conn.(*tls.Conn); ok {
+			if !stream.handshake(tlsConn) {
+				return nil
+			}
+		}
+		var srcIP string
+		if conn, ok := conn.(net.Conn); ok {
+			srcIP, _, _ = net.SplitHostPort(conn.RemoteAddr().String())
+		}
+		dstAddr, hostname, release, err := stream.upstream(srcIP)
+		if err != nil {
+			return err
+		}
+		dstConn, err := stream.dialUpstream(conn, dstAddr, hostname)
 		if err != nil {
+			release(err)
 			return err
 		}
+		defer release(nil)
 		defer dstConn.Close()
-		defer conn.Close()
 		pipe := U.NewBidirectionalPipe(stream.task.Context(), conn, dstConn)
 		return pipe.Start()
 	default:
@@ -124,6 +158,128 @@ func (stream *Stream) Handle(conn types.StreamConn) error {
 	}
 }
 
+// dstAddr returns the address to forward a new connection from srcIP to,
+// and a function to call when the connection is closed.
+func (stream *Stream) dstAddr(srcIP string) (dstAddr net.Addr, release func(err error), err error) {
+	dstAddr, _, release, err = stream.upstream(srcIP)
+	return dstAddr, release, err
+}
+
+// upstream is like dstAddr, and also returns the host name of the upstream,
+// i.e. of the server selected by the load balancer.
+func (stream *Stream) upstream(srcIP string) (dstAddr net.Addr, hostname string, release func(err error), err error) {
+	if stream.loadBalancer == nil {
+		if stream.ProxyURL != nil {
+			hostname = stream.ProxyURL.Hostname()
+		}
+		return stream.targetAddr, hostname, func(error) {}, nil
+	}
+	srv, release, err := stream.loadBalancer.NextStreamServer(srcIP)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if stream.Scheme == "udp" {
+		dstAddr, err = net.ResolveUDPAddr("udp", srv.URL().Host)
+	} else {
+		dstAddr, err = resolveUpstreamAddr(srv.URL())
+	}
+	if err != nil {
+		release(err)
+		return nil, "", nil, err
+	}
+	return dstAddr, srv.URL().Hostname(), release, nil
+}
+
+// resolveUpstreamAddr resolves the address of a tcp based upstream,
+// which is either host:port or a unix socket.
+func resolveUpstreamAddr(u *types.URL) (net.Addr, error) {
+	if u.Scheme == "unix" {
+		return &net.UnixAddr{Name: u.Path, Net: "unix"}, nil
+	}
+	return net.ResolveTCPAddr("tcp", u.Host)
+}
+
 func (stream *Stream) Close() error {
 	return stream.listener.Close()
 }
+
+// dialUpstream dials the upstream, sends the PROXY protocol header if enabled,
+// and starts TLS for the tcp+tls scheme, verifying the certificate against hostname.
+func (stream *Stream) dialUpstream(conn io.ReadWriteCloser, dstAddr net.Addr, hostname string) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(stream.task.Context(), streamDialTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	dstConn, err := dialer.DialContext(ctx, dstAddr.Network(), dstAddr.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.sendProxyProtocol(conn, dstConn); err != nil {
+		dstConn.Close()
+		return nil, err
+	}
+	if stream.Scheme != "tcp+tls" {
+		return dstConn, nil
+	}
+
+	serverName := hostname
+	if serverName == "" {
+		serverName, _, _ = net.SplitHostPort(dstAddr.String())
+	}
+	tlsConn := tls.Client(dstConn, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: stream.NoTLSVerify,
+		MinVersion:         tls.VersionTLS12,
+	})
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		dstConn.Close()
+		return nil, fmt.Errorf("upstream tls handshake: %w", err)
+	}
+	return tlsConn, nil
+}
+
+// listenerTLSConfig returns the TLS config of the listener with certificates from autocert.
+func (stream *Stream) listenerTLSConfig() (*tls.Config, error) {
+	cfg := config.GetInstance()
+	if cfg == nil || cfg.AutoCertProvider() == nil {
+		return nil, fmt.Errorf("%s scheme requires autocert to be configured", stream.Scheme)
+	}
+	return &tls.Config{
+		GetCertificate: cfg.AutoCertProvider().GetCert,
+		MinVersion:     tls.VersionTLS12,
+	}, nil
+}
+
+// handshake completes the TLS handshake of the client connection,
+// returns false and logs the SNI and error if it fails.
+func (stream *Stream) handshake(conn *tls.Conn) bool {
+	ctx, cancel := context.WithTimeout(stream.task.Context(), streamTLSHandshakeTimeout)
+	defer cancel()
+
+	err := conn.HandshakeContext(ctx)
+	state := conn.ConnectionState()
+	if err != nil {
+		stream.l.Warn().Err(err).
+			Str("sni", state.ServerName).
+			Str("remote", conn.RemoteAddr().String()).
+			Msg("tls handshake failed")
+		return false
+	}
+	stream.l.Debug().
+		Str("sni", state.ServerName).
+		Str("remote", conn.RemoteAddr().String()).
+		Msg("tls handshake completed")
+	return true
+}
+
+// sendProxyProtocol sends the PROXY protocol header with the client address to the upstream if enabled.
+func (stream *Stream) sendProxyProtocol(conn io.ReadWriteCloser, dstConn net.Conn) error {
+	if stream.ProxyProtocol == nil || stream.ProxyProtocol.Send == 0 {
+		return nil
+	}
+	var src, dst net.Addr
+	if conn, ok := conn.(net.Conn); ok {
+		src, dst = conn.RemoteAddr(), conn.LocalAddr()
+	}
+	return proxyproto.WriteHeader(dstConn, stream.ProxyProtocol.Send, src, dst)
+}
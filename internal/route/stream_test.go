@@ -0,0 +1,209 @@
+package route
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/yusing/go-proxy/internal/net/gphttp/loadbalancer"
+	loadbalance "github.com/yusing/go-proxy/internal/net/gphttp/loadbalancer/types"
+	nettypes "github.com/yusing/go-proxy/internal/net/types"
+	route "github.com/yusing/go-proxy/internal/route/types"
+	"github.com/yusing/go-proxy/internal/task"
+	expect "github.com/yusing/go-proxy/internal/utils/testing"
+	"github.com/yusing/go-proxy/internal/watcher/health"
+)
+
+// healthyMonitor reports the server as always healthy.
+type healthyMonitor struct {
+	health.HealthMonitor
+}
+
+func (healthyMonitor) Status() health.Status {
+	return health.StatusHealthy
+}
+
+// newTestStreamLB starts a round robin load balanced stream route listening on a random port,
+// with a server for each upstream address.
+func newTestStreamLB(t *testing.T, scheme route.Scheme, upstreams ...string) (*StreamRoute, []loadbalance.Server) {
+	t.Helper()
+	parent := task.RootTask("test", false)
+	t.Cleanup(func() { parent.Finish(nil) })
+
+	lb := loadbalancer.New(&loadbalance.Config{Link: "test", Mode: loadbalance.ModeRoundRobin})
+	expect.NoError(t, lb.Start(parent))
+
+	srvs := make([]loadbalance.Server, len(upstreams))
+	for i, addr := range upstreams {
+		u := nettypes.MustParseURL(string(scheme) + "://" + addr)
+		srvs[i] = loadbalance.NewServer(addr, u, 1, "", "", "", nil, healthyMonitor{})
+		lb.AddServer(srvs[i])
+	}
+
+	r := &StreamRoute{
+		Route: &Route{
+			Alias:    "test",
+			Scheme:   scheme,
+			Metadata: Metadata{LisURL: nettypes.MustParseURL(string(scheme) + "://127.0.0.1:0")},
+		},
+		loadBalancer: lb,
+		task:         lb.Task(),
+	}
+	r.Stream = NewStream(r)
+	expect.NoError(t, r.Setup())
+	go r.acceptConnections()
+	return r, srvs
+}
+
+// tcpEchoName accepts connections and writes name to them.
+func tcpEchoName(t *testing.T, name string) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	expect.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = io.WriteString(conn, name)
+			conn.Close()
+		}
+	}()
+	return l.Addr().String()
+}
+
+// udpEchoName replies name to every packet.
+func udpEchoName(t *testing.T, name string) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	expect.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			_, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			_, _ = conn.WriteTo([]byte(name), addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func readName(t *testing.T, network, addr string) string {
+	t.Helper()
+	conn, err := net.Dial(network, addr)
+	expect.NoError(t, err)
+	defer conn.Close()
+	expect.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+	if network == "udp" {
+		_, err = conn.Write([]byte("ping"))
+		expect.NoError(t, err)
+		buf := make([]byte, 64)
+		n, err := conn.Read(buf)
+		expect.NoError(t, err)
+		return string(buf[:n])
+	}
+	name, err := io.ReadAll(conn)
+	expect.NoError(t, err)
+	return string(name)
+}
+
+func TestStreamLoadBalance(t *testing.T) {
+	tests := []struct {
+		scheme route.Scheme
+		echo   func(t *testing.T, name string) string
+	}{
+		{"tcp", tcpEchoName},
+		{"udp", udpEchoName},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.scheme), func(t *testing.T) {
+			r, srvs := newTestStreamLB(t, tt.scheme, tt.echo(t, "a"), tt.echo(t, "b"))
+			addr := r.Addr().String()
+
+			got := map[string]int{}
+			for range 4 {
+				got[readName(t, string(tt.scheme), addr)]++
+			}
+			expect.Equal(t, got, map[string]int{"a": 2, "b": 2})
+			for _, srv := range srvs {
+				expect.Equal(t, srv.Stats().Snapshot().Requests, 2)
+			}
+		})
+	}
+}
+
+func TestStreamLoadBalanceReleaseOnDialFailure(t *testing.T) {
+	// reserve a port and close it so dialing it fails
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	expect.NoError(t, err)
+	deadAddr := l.Addr().String()
+	l.Close()
+
+	r, srvs := newTestStreamLB(t, "tcp", deadAddr)
+	conn, err := net.Dial("tcp", r.Addr().String())
+	expect.NoError(t, err)
+	defer conn.Close()
+	expect.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+	// the connection is closed after release is called
+	_, err = io.ReadAll(conn)
+	expect.NoError(t, err)
+
+	stats := srvs[0].Stats().Snapshot()
+	expect.Equal(t, stats.Requests, 1)
+	expect.Equal(t, stats.Active, 0)
+	expect.Equal(t, stats.Errors, 1)
+}
+
+func TestStreamUpstreamTLSServerName(t *testing.T) {
+	sni := make(chan string, 1)
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			sni <- hello.ServerName
+			return nil, errors.New("done")
+		},
+	})
+	expect.NoError(t, err)
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.(*tls.Conn).Handshake()
+	}()
+
+	parent := task.RootTask("test", false)
+	defer parent.Finish(nil)
+	lb := loadbalancer.New(&loadbalance.Config{Link: "test"})
+	expect.NoError(t, lb.Start(parent))
+	lb.AddServer(loadbalance.NewServer("upstream", nettypes.MustParseURL("tcp://localhost:443"), 1, "", "", "", nil, healthyMonitor{}))
+
+	stream := NewStream(&StreamRoute{
+		Route: &Route{
+			Alias:      "test",
+			Scheme:     route.SchemeTCPTLS,
+			HTTPConfig: route.HTTPConfig{NoTLSVerify: true},
+		},
+		loadBalancer: lb,
+		task:         lb.Task(),
+	})
+
+	// the host name of the server selected by the load balancer, not the resolved IP
+	_, hostname, release, err := stream.upstream("127.0.0.1")
+	expect.NoError(t, err)
+	release(nil)
+	expect.Equal(t, hostname, "localhost")
+
+	_, err = stream.dialUpstream(nil, l.Addr(), hostname)
+	expect.HasError(t, err)
+	expect.Equal(t, <-sni, "localhost")
+}
@@ -0,0 +1,121 @@
+package route
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/yusing/go-proxy/internal/gperr"
+	"github.com/yusing/go-proxy/internal/net/proxyproto"
+	"github.com/yusing/go-proxy/internal/route/routes"
+	"github.com/yusing/go-proxy/internal/task"
+	U "github.com/yusing/go-proxy/internal/utils"
+	"github.com/yusing/go-proxy/internal/watcher/health"
+	"github.com/yusing/go-proxy/internal/watcher/health/monitor"
+)
+
+// TLSPassthroughRoute forwards TLS connections with a matching SNI
+// to the upstream without terminating TLS.
+//
+// Plain HTTP requests to the route are redirected to HTTPS.
+type TLSPassthroughRoute struct {
+	*Route
+
+	HealthMon health.HealthMonitor `json:"health"`
+
+	dialer *net.Dialer
+	task   *task.Task
+
+	l zerolog.Logger
+}
+
+const tlsPassthroughDialTimeout = 5 * time.Second
+
+func NewTLSPassthroughRoute(base *Route) (*TLSPassthroughRoute, gperr.Error) {
+	return &TLSPassthroughRoute{
+		Route:  base,
+		dialer: &net.Dialer{Timeout: tlsPassthroughDialTimeout},
+		l: log.With().
+			Str("type", string(base.Scheme)).
+			Str("name", base.Name()).
+			Logger(),
+	}, nil
+}
+
+// Start implements task.TaskStarter.
+func (r *TLSPassthroughRoute) Start(parent task.Parent) gperr.Error {
+	if existing, ok := routes.HTTP.Get(r.Key()); ok {
+		return gperr.Errorf("route already exists: from provider %s and %s", existing.ProviderName(), r.ProviderName())
+	}
+	r.task = parent.Subtask("tls."+r.Name(), false)
+
+	if r.UseHealthCheck() {
+		r.HealthMon = monitor.NewMonitor(r)
+		if err := r.HealthMon.Start(r.task); err != nil {
+			return err
+		}
+	}
+
+	routes.HTTP.Add(r)
+	r.task.OnFinished("entrypoint_remove_route", func() {
+		routes.HTTP.Del(r)
+	})
+	return nil
+}
+
+// Task implements task.TaskStarter.
+func (r *TLSPassthroughRoute) Task() *task.Task {
+	return r.task
+}
+
+// Finish implements task.TaskFinisher.
+func (r *TLSPassthroughRoute) Finish(reason any) {
+	r.task.Finish(reason)
+}
+
+func (r *TLSPassthroughRoute) HealthMonitor() health.HealthMonitor {
+	return r.HealthMon
+}
+
+// ServeHTTP implements http.Handler.
+//
+// TLS connections of the route never reach the HTTP handler unless the client did not send SNI,
+// so plain HTTP requests are redirected to HTTPS and the rest are rejected.
+func (r *TLSPassthroughRoute) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.TLS == nil {
+		http.Redirect(w, req, "https://"+req.Host+req.URL.RequestURI(), http.StatusPermanentRedirect)
+		return
+	}
+	http.Error(w, "TLS passthrough route requires SNI", http.StatusMisdirectedRequest)
+}
+
+// HandleTLSConn implements routes.TLSPassthroughRoute.
+func (r *TLSPassthroughRoute) HandleTLSConn(conn io.ReadWriteCloser) error {
+	defer conn.Close()
+
+	network, addr := "tcp", r.ProxyURL.Host
+	if socket := r.UnixSocket(); socket != "" {
+		network, addr = "unix", socket
+	}
+	dstConn, err := r.dialer.DialContext(r.task.Context(), network, addr)
+	if err != nil {
+		return err
+	}
+	defer dstConn.Close()
+
+	if pp := r.ProxyProtocol; pp != nil && pp.Send != 0 {
+		var src, dst net.Addr
+		if conn, ok := conn.(net.Conn); ok {
+			src, dst = conn.RemoteAddr(), conn.LocalAddr()
+		}
+		if err := proxyproto.WriteHeader(dstConn, pp.Send, src, dst); err != nil {
+			return err
+		}
+	}
+
+	pipe := U.NewBidirectionalPipe(r.task.Context(), conn, dstConn)
+	return pipe.Start()
+}
@@ -0,0 +1,86 @@
+package route
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/gobwas/glob"
+	"github.com/yusing/go-proxy/internal/gperr"
+)
+
+// FileServerConfig is the config of file server routes.
+type FileServerConfig struct {
+	// SPA serves the root index file for paths not found, i.e. client side routes of single page apps.
+	//
+	// Paths with a file extension are still not found, so missing assets are not answered with the index.
+	SPA bool `json:"spa,omitempty"`
+	// Precompressed serves the `.br`, `.zst` or `.gz` sibling of a file if accepted by the client.
+	Precompressed bool `json:"precompressed,omitempty"`
+	// CacheControl is the Cache-Control header of responses by glob of the request path, e.g.
+	//
+	//	/assets/*: public, max-age=31536000, immutable
+	//	*.html: no-cache
+	//
+	// The longest matching pattern wins.
+	CacheControl map[string]string `json:"cache_control,omitempty"`
+	// ETag sends strong ETags of the file contents instead of relying on the modification time.
+	ETag bool `json:"etag,omitempty"`
+	// IndexFiles is the file names served for directories, the first existing one wins.
+	// Defaults to `index.html`.
+	IndexFiles []string `json:"index_files,omitempty"`
+	// DisableListing responds not found for directories without an index file.
+	DisableListing bool `json:"disable_listing,omitempty"`
+	// HideDotfiles responds not found for files and directories starting with a dot, e.g. `.env` and `.git`.
+	HideDotfiles bool `json:"hide_dotfiles,omitempty"`
+
+	cacheControl []cacheControlRule
+}
+
+type cacheControlRule struct {
+	pattern string
+	glob    glob.Glob
+	value   string
+}
+
+const FileServerIndexDefault = "index.html"
+
+// Validate implements serialization.CustomValidator.
+func (cfg *FileServerConfig) Validate() gperr.Error {
+	if len(cfg.IndexFiles) == 0 {
+		cfg.IndexFiles = []string{FileServerIndexDefault}
+	}
+
+	errs := gperr.NewBuilder("invalid file server config")
+	for _, name := range cfg.IndexFiles {
+		if name == "" || strings.ContainsRune(name, '/') {
+			errs.Add(gperr.New("index file must be a file name").Subject(name))
+		}
+	}
+
+	cfg.cacheControl = make([]cacheControlRule, 0, len(cfg.CacheControl))
+	for pattern, value := range cfg.CacheControl {
+		g, err := glob.Compile(pattern)
+		if err != nil {
+			errs.Add(gperr.Wrap(err).Subject(pattern))
+			continue
+		}
+		cfg.cacheControl = append(cfg.cacheControl, cacheControlRule{pattern: pattern, glob: g, value: value})
+	}
+	slices.SortFunc(cfg.cacheControl, func(a, b cacheControlRule) int {
+		if n := len(b.pattern) - len(a.pattern); n != 0 {
+			return n
+		}
+		return strings.Compare(a.pattern, b.pattern)
+	})
+	return errs.Error()
+}
+
+// CacheControlOf returns the Cache-Control header of the request path, empty if no pattern matches.
+func (cfg *FileServerConfig) CacheControlOf(path string) string {
+	for _, rule := range cfg.cacheControl {
+		if rule.glob.Match(path) {
+			return rule.value
+		}
+	}
+	return ""
+}
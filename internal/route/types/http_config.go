@@ -2,10 +2,70 @@ package route
 
 import (
 	"time"
+
+	gphttp "github.com/yusing/go-proxy/internal/net/gphttp"
 )
 
 type HTTPConfig struct {
-	NoTLSVerify           bool          `json:"no_tls_verify,omitempty"`
+	NoTLSVerify bool `json:"no_tls_verify,omitempty"`
+	// SSLServerName overrides the server name to verify the upstream certificate against and to send in SNI.
+	SSLServerName string `json:"ssl_server_name,omitempty"`
+	// SSLCA is the path of PEM encoded CA certificates to verify the upstream certificate with,
+	// e.g. of a private CA, instead of the system CA certificates.
+	SSLCA string `json:"ssl_ca,omitempty"`
+	// SSLCert and SSLKey are the paths of the PEM encoded client certificate and key for mutual TLS.
+	//
+	// For routes on agents, paths of SSLCA, SSLCert and SSLKey are on the agent host.
+	SSLCert string `json:"ssl_cert,omitempty"`
+	SSLKey  string `json:"ssl_key,omitempty"`
+	// SSLProtocols is the allowed TLS versions, e.g. `tlsv1.2` and `tlsv1.3`.
+	SSLProtocols []string `json:"ssl_protocols,omitempty"`
+
+	DialTimeout time.Duration `json:"dial_timeout,omitempty"`
+	// KeepAlive is the interval of TCP keep-alive probes, negative to disable.
+	KeepAlive           time.Duration `json:"keep_alive,omitempty"`
+	DisableKeepAlives   bool          `json:"disable_keep_alives,omitempty"`
+	MaxIdleConns        int           `json:"max_idle_conns,omitempty" validate:"omitempty,gte=0"`
+	MaxIdleConnsPerHost int           `json:"max_idle_conns_per_host,omitempty" validate:"omitempty,gte=0"`
+	MaxConnsPerHost     int           `json:"max_conns_per_host,omitempty" validate:"omitempty,gte=0"`
+	IdleConnTimeout     time.Duration `json:"idle_conn_timeout,omitempty"`
+	// HTTPVersion forces the HTTP version of requests to the upstream, `1.1` or `2`.
+	HTTPVersion string `json:"http_version,omitempty" validate:"omitempty,oneof=1.1 2"`
+
 	ResponseHeaderTimeout time.Duration `json:"response_header_timeout,omitempty"`
-	DisableCompression    bool          `json:"disable_compression,omitempty"`
+	// RequestTimeout limits the time of the whole request including reading the response body,
+	// leave it unset for long-lived streams, e.g. websockets and server-sent events.
+	RequestTimeout time.Duration `json:"request_timeout,omitempty"`
+	// BufferRequestBody reads the whole request body before sending it to the upstream,
+	// for upstreams that cannot handle slow or chunked uploads.
+	BufferRequestBody bool `json:"buffer_request_body,omitempty"`
+	// HostHeader overrides the Host header of requests to the upstream.
+	HostHeader         string `json:"host_header,omitempty"`
+	DisableCompression bool   `json:"disable_compression,omitempty"`
+}
+
+// UpstreamTLSOptions returns the options of TLS connections to the upstream.
+func (cfg *HTTPConfig) UpstreamTLSOptions() *gphttp.UpstreamTLSOptions {
+	return &gphttp.UpstreamTLSOptions{
+		SkipVerify: cfg.NoTLSVerify,
+		ServerName: cfg.SSLServerName,
+		CA:         cfg.SSLCA,
+		Cert:       cfg.SSLCert,
+		Key:        cfg.SSLKey,
+		Protocols:  cfg.SSLProtocols,
+	}
+}
+
+// TransportOptions returns the options of connections to the upstream.
+func (cfg *HTTPConfig) TransportOptions() *gphttp.TransportOptions {
+	return &gphttp.TransportOptions{
+		DialTimeout:         cfg.DialTimeout,
+		KeepAlive:           cfg.KeepAlive,
+		DisableKeepAlives:   cfg.DisableKeepAlives,
+		MaxIdleConns:        cfg.MaxIdleConns,
+		MaxIdleConnsPerHost: cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     cfg.MaxConnsPerHost,
+		IdleConnTimeout:     cfg.IdleConnTimeout,
+		HTTPVersion:         cfg.HTTPVersion,
+	}
 }
@@ -0,0 +1,29 @@
+package route
+
+import (
+	"path"
+	"strings"
+
+	"github.com/yusing/go-proxy/internal/gperr"
+)
+
+// PathRouteConfig mounts a route under a path prefix of a host composed of multiple routes,
+// e.g. `app.example.com/api` to one route and `app.example.com/` to another.
+type PathRouteConfig struct {
+	// Link is the alias of the composed host, e.g. `app` for `app.example.com`.
+	Link string `json:"link" validate:"required"`
+	// Prefix is the path prefix of the route, e.g. `/api`.
+	// Requests go to the route with the longest matching prefix.
+	Prefix string `json:"prefix" validate:"required"`
+	// StripPrefix removes the prefix from the request path before passing it to the route.
+	StripPrefix bool `json:"strip_prefix,omitempty"`
+}
+
+// Validate implements serialization.CustomValidator.
+func (cfg *PathRouteConfig) Validate() gperr.Error {
+	if !strings.HasPrefix(cfg.Prefix, "/") {
+		return gperr.New("path prefix must start with /").Subject(cfg.Prefix)
+	}
+	cfg.Prefix = path.Clean(cfg.Prefix)
+	return nil
+}
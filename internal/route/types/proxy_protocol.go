@@ -0,0 +1,15 @@
+package route
+
+import (
+	"github.com/yusing/go-proxy/internal/net/proxyproto"
+)
+
+// ProxyProtocolConfig is the PROXY protocol config of a route.
+type ProxyProtocolConfig struct {
+	// Accept accepts PROXY protocol headers on the listening port of stream routes.
+	//
+	// For HTTP routes, use `entrypoint.proxy_protocol` instead.
+	Accept *proxyproto.Config `json:"accept,omitempty"`
+	// Send sends a PROXY protocol header of this version (1 or 2) to the upstream, 0 to disable.
+	Send proxyproto.Version `json:"send,omitempty" validate:"omitempty,oneof=1 2"`
+}
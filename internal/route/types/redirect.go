@@ -0,0 +1,33 @@
+package route
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/yusing/go-proxy/internal/gperr"
+)
+
+// RedirectConfig is the config of redirect routes.
+type RedirectConfig struct {
+	// To is the URL template to redirect to, e.g. `https://new.example.com$req_uri`.
+	//
+	// Request variables like `$req_host`, `$req_path` and `$arg(name)` are replaced.
+	To string `json:"to" validate:"required"`
+	// Code is the status code of the redirect, default 308.
+	Code int `json:"code,omitempty" validate:"omitempty,oneof=301 302 303 307 308"`
+	// PreservePath appends the request path and query to the target URL.
+	PreservePath bool `json:"preserve_path,omitempty"`
+}
+
+const RedirectCodeDefault = http.StatusPermanentRedirect
+
+// Validate implements serialization.CustomValidator.
+func (cfg *RedirectConfig) Validate() gperr.Error {
+	if cfg.Code == 0 {
+		cfg.Code = RedirectCodeDefault
+	}
+	if cfg.PreservePath && strings.Contains(cfg.To, "?") {
+		return gperr.New("preserve_path cannot be used with a target URL with query")
+	}
+	return nil
+}
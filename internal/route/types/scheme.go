@@ -9,21 +9,62 @@ type Scheme string
 var ErrInvalidScheme = gperr.New("invalid scheme")
 
 const (
-	SchemeHTTP       Scheme = "http"
-	SchemeHTTPS      Scheme = "https"
+	SchemeHTTP  Scheme = "http"
+	SchemeHTTPS Scheme = "https"
+	// SchemeH2C proxies HTTP/2 over cleartext (prior knowledge) to the upstream.
+	SchemeH2C Scheme = "h2c"
+	// SchemeGRPC and SchemeGRPCS proxy gRPC over h2c and HTTP/2 over TLS,
+	// with gRPC health checks and gRPC error responses.
+	SchemeGRPC       Scheme = "grpc"
+	SchemeGRPCS      Scheme = "grpcs"
 	SchemeTCP        Scheme = "tcp"
 	SchemeUDP        Scheme = "udp"
 	SchemeFileServer Scheme = "fileserver"
+	// SchemeRedirect redirects requests to a URL template.
+	SchemeRedirect Scheme = "redirect"
+	// SchemeStatic responds with a fixed status code, headers and body or file.
+	SchemeStatic Scheme = "static"
+	// SchemeTLS terminates TLS on the listening port and forwards plain TCP to the upstream.
+	SchemeTLS Scheme = "tls"
+	// SchemeTCPTLS terminates TLS on the listening port and re-encrypts to the upstream.
+	SchemeTCPTLS Scheme = "tcp+tls"
+	// SchemeTLSPassthrough forwards TLS connections on the HTTPS port to the upstream as is, routed by SNI.
+	SchemeTLSPassthrough Scheme = "tls-passthrough"
 )
 
 func (s Scheme) Validate() gperr.Error {
 	switch s {
-	case SchemeHTTP, SchemeHTTPS,
-		SchemeTCP, SchemeUDP, SchemeFileServer:
+	case SchemeHTTP, SchemeHTTPS, SchemeH2C, SchemeGRPC, SchemeGRPCS,
+		SchemeTCP, SchemeUDP, SchemeFileServer, SchemeRedirect, SchemeStatic,
+		SchemeTLS, SchemeTCPTLS, SchemeTLSPassthrough:
 		return nil
 	}
 	return ErrInvalidScheme.Subject(string(s))
 }
 
-func (s Scheme) IsReverseProxy() bool { return s == SchemeHTTP || s == SchemeHTTPS }
-func (s Scheme) IsStream() bool       { return s == SchemeTCP || s == SchemeUDP }
+func (s Scheme) IsReverseProxy() bool {
+	return s.HTTPScheme() == SchemeHTTP || s.HTTPScheme() == SchemeHTTPS
+}
+func (s Scheme) IsGRPC() bool   { return s == SchemeGRPC || s == SchemeGRPCS }
+func (s Scheme) IsStream() bool { return s == SchemeTCP || s == SchemeUDP || s.IsTLSStream() }
+
+// HasUpstream returns whether requests of the scheme are proxied to an upstream,
+// instead of being responded by GoDoxy itself.
+func (s Scheme) HasUpstream() bool {
+	return s != SchemeFileServer && s != SchemeRedirect && s != SchemeStatic
+}
+
+// IsTLSStream returns whether the scheme is a stream that terminates TLS on the listening port.
+func (s Scheme) IsTLSStream() bool { return s == SchemeTLS || s == SchemeTCPTLS }
+
+// HTTPScheme returns the scheme of the upstream URL for reverse proxy schemes,
+// i.e. `http` for cleartext and `https` for TLS.
+func (s Scheme) HTTPScheme() Scheme {
+	switch s {
+	case SchemeH2C, SchemeGRPC:
+		return SchemeHTTP
+	case SchemeGRPCS:
+		return SchemeHTTPS
+	}
+	return s
+}
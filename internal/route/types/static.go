@@ -0,0 +1,37 @@
+package route
+
+import (
+	"net/http"
+	"path/filepath"
+
+	"github.com/yusing/go-proxy/internal/gperr"
+)
+
+// StaticConfig is the config of static response routes.
+type StaticConfig struct {
+	// StatusCode is the status code of the response, default 200.
+	StatusCode int `json:"status_code,omitempty" validate:"omitempty,gte=200,lte=599"`
+	// Headers are the headers of the response.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is the response body.
+	Body string `json:"body,omitempty"`
+	// File is the absolute path of the file to respond with, mutually exclusive with Body.
+	File string `json:"file,omitempty"`
+}
+
+// Validate implements serialization.CustomValidator.
+func (cfg *StaticConfig) Validate() gperr.Error {
+	if cfg.StatusCode == 0 {
+		cfg.StatusCode = http.StatusOK
+	}
+	if cfg.File != "" {
+		if cfg.Body != "" {
+			return gperr.New("body and file are mutually exclusive")
+		}
+		if !filepath.IsAbs(cfg.File) {
+			return gperr.New("file must be an absolute path").Subject(cfg.File)
+		}
+		cfg.File = filepath.Clean(cfg.File)
+	}
+	return nil
+}
@@ -16,14 +16,20 @@ type (
 	UDPForwarder struct {
 		ctx       context.Context
 		forwarder *net.UDPConn
-		dstAddr   net.Addr
+		dstAddr   UDPDstAddrFunc
 		connMap   F.Map[string, *UDPConn]
 		mu        sync.Mutex
 	}
-	UDPConn struct {
+	// UDPDstAddrFunc returns the address to forward packets from srcIP to,
+	// and a function to call when the connection is closed.
+	UDPDstAddrFunc func(srcIP string) (dstAddr net.Addr, release func(err error), err error)
+	UDPConn        struct {
 		srcAddr *net.UDPAddr
 		conn    net.Conn
 		buf     *UDPBuf
+
+		release     func(err error)
+		releaseOnce sync.Once
 	}
 	UDPBuf struct {
 		data, oob []byte
@@ -33,7 +39,7 @@ type (
 
 const udpConnBufferSize = 4096
 
-func NewUDPForwarder(ctx context.Context, forwarder *net.UDPConn, dstAddr net.Addr) *UDPForwarder {
+func NewUDPForwarder(ctx context.Context, forwarder *net.UDPConn, dstAddr UDPDstAddrFunc) *UDPForwarder {
 	return &UDPForwarder{
 		ctx:       ctx,
 		forwarder: forwarder,
@@ -69,20 +75,38 @@ func (w *UDPForwarder) Accept() (types.StreamConn, error) {
 	}, nil
 }
 
-func (w *UDPForwarder) dialDst() (dstConn net.Conn, err error) {
-	switch dstAddr := w.dstAddr.(type) {
+func (w *UDPForwarder) dialDst(srcAddr *net.UDPAddr) (dstConn net.Conn, release func(err error), err error) {
+	dstAddr, release, err := w.dstAddr(srcAddr.IP.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	switch dstAddr := dstAddr.(type) {
 	case *net.UDPAddr:
 		var laddr *net.UDPAddr
 		if dstAddr.IP.IsLoopback() {
 			laddr, _ = net.ResolveUDPAddr(dstAddr.Network(), "127.0.0.1:")
 		}
-		dstConn, err = net.DialUDP(w.dstAddr.Network(), laddr, dstAddr)
+		dstConn, err = net.DialUDP(dstAddr.Network(), laddr, dstAddr)
 	case *net.TCPAddr:
-		dstConn, err = net.DialTCP(w.dstAddr.Network(), nil, dstAddr)
+		dstConn, err = net.DialTCP(dstAddr.Network(), nil, dstAddr)
 	default:
-		err = fmt.Errorf("unsupported network %s", w.dstAddr.Network())
+		err = fmt.Errorf("unsupported network %s", dstAddr.Network())
 	}
-	return
+	if err != nil {
+		release(err)
+		return nil, nil, err
+	}
+	return dstConn, release, nil
+}
+
+// close closes the connection to the destination.
+func (conn *UDPConn) close() error {
+	if conn.release != nil {
+		conn.releaseOnce.Do(func() {
+			conn.release(nil)
+		})
+	}
+	return conn.conn.Close()
 }
 
 func (w *UDPForwarder) readFromListener(buf *UDPBuf) (srcAddr *net.UDPAddr, err error) {
@@ -140,12 +164,12 @@ func (w *UDPForwarder) getInitConn(conn *UDPConn, key string) (*UDPConn, error)
 	if !ok {
 		var err error
 		dst = conn
-		dst.conn, err = w.dialDst()
+		dst.conn, dst.release, err = w.dialDst(conn.srcAddr)
 		if err != nil {
 			return nil, err
 		}
 		if err := dst.write(); err != nil {
-			dst.conn.Close()
+			dst.close()
 			return nil, err
 		}
 		w.connMap.Store(key, dst)
@@ -153,7 +177,7 @@ func (w *UDPForwarder) getInitConn(conn *UDPConn, key string) (*UDPConn, error)
 		conn.conn = dst.conn
 		if err := conn.write(); err != nil {
 			w.connMap.Delete(key)
-			dst.conn.Close()
+			dst.close()
 			return nil, err
 		}
 	}
@@ -180,7 +204,7 @@ func (w *UDPForwarder) Handle(streamConn types.StreamConn) error {
 		default:
 			if err := dst.read(); err != nil {
 				w.connMap.Delete(key)
-				dst.conn.Close()
+				dst.close()
 				return err
 			}
 
@@ -196,7 +220,7 @@ func (w *UDPForwarder) Close() error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 	w.connMap.RangeAll(func(key string, conn *UDPConn) {
-		errs.Add(conn.conn.Close())
+		errs.Add(conn.close())
 	})
 	w.connMap.Clear()
 	errs.Add(w.forwarder.Close())
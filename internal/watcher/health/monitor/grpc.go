@@ -0,0 +1,240 @@
+package monitor
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/yusing/go-proxy/internal/watcher/health"
+	"github.com/yusing/go-proxy/pkg"
+)
+
+// GRPCHealthMonitor checks the health of gRPC upstreams with the standard health checking protocol.
+//
+// The service to check is `path` of the health check config without the leading slash,
+// e.g. `/my.package.Service`. If empty, the overall health of the server is checked.
+//
+// https://github.com/grpc/grpc/blob/master/doc/health-checking.md
+type GRPCHealthMonitor struct {
+	*monitor
+	client  *http.Client
+	service string
+}
+
+// grpc.health.v1.HealthCheckResponse.ServingStatus
+const (
+	grpcServingStatusUnknown        = 0
+	grpcServingStatusServing        = 1
+	grpcServingStatusNotServing     = 2
+	grpcServingStatusServiceUnknown = 3
+)
+
+const (
+	grpcHealthCheckPath   = "/grpc.health.v1.Health/Check"
+	grpcMaxResponseLength = 64 * 1024
+)
+
+var (
+	// h2cPinger speaks HTTP/2 over cleartext for grpc:// upstreams.
+	h2cPinger = newGRPCPinger(false)
+	// h2Pinger speaks HTTP/2 over TLS for grpcs:// upstreams.
+	h2Pinger = newGRPCPinger(true)
+)
+
+var errInvalidGRPCResponse = errors.New("invalid grpc health check response")
+
+func newGRPCPinger(useTLS bool) *http.Client {
+	tr := &http.Transport{
+		// like the http pinger, certificate errors do not make the upstream unhealthy
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec
+		Protocols:       new(http.Protocols),
+	}
+	if useTLS {
+		tr.Protocols.SetHTTP2(true)
+	} else {
+		tr.Protocols.SetUnencryptedHTTP2(true)
+	}
+	return &http.Client{Transport: tr}
+}
+
+func NewGRPCHealthMonitor(url *url.URL, config *health.HealthCheckConfig) *GRPCHealthMonitor {
+	mon := new(GRPCHealthMonitor)
+	mon.monitor = newMonitor(url, config, mon.CheckHealth)
+	mon.service = strings.TrimPrefix(config.Path, "/")
+	if url.Scheme == "grpcs" {
+		mon.client = h2Pinger
+	} else {
+		mon.client = h2cPinger
+	}
+	return mon
+}
+
+func (mon *GRPCHealthMonitor) CheckHealth() (*health.HealthCheckResult, error) {
+	ctx, cancel := mon.ContextWithTimeout("ping request timed out")
+	defer cancel()
+
+	u := *mon.url.Load()
+	if u.Scheme == "grpcs" {
+		u.Scheme = "https"
+	} else {
+		u.Scheme = "http"
+	}
+	u.Path = grpcHealthCheckPath
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(encodeGRPCHealthCheckRequest(mon.service)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("Te", "trailers")
+	req.Header.Set("User-Agent", "GoDoxy/"+pkg.GetVersion().String())
+
+	start := time.Now()
+	resp, err := mon.client.Do(req)
+	lat := time.Since(start)
+	if err != nil {
+		return &health.HealthCheckResult{
+			Latency: lat,
+			Detail:  err.Error(),
+		}, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &health.HealthCheckResult{
+			Latency: lat,
+			Detail:  resp.Status,
+		}, nil
+	}
+
+	// read until EOF to receive the trailers
+	body, err := io.ReadAll(io.LimitReader(resp.Body, grpcMaxResponseLength))
+	if err != nil {
+		return &health.HealthCheckResult{
+			Latency: lat,
+			Detail:  err.Error(),
+		}, nil
+	}
+
+	// trailers-only responses have the status in headers
+	status := resp.Trailer.Get("Grpc-Status")
+	msg := resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status = resp.Header.Get("Grpc-Status")
+		msg = resp.Header.Get("Grpc-Message")
+	}
+	if status != "0" {
+		if unescaped, err := url.PathUnescape(msg); err == nil {
+			msg = unescaped
+		}
+		return &health.HealthCheckResult{
+			Latency: lat,
+			Detail:  fmt.Sprintf("grpc-status %s: %s", status, msg),
+		}, nil
+	}
+
+	servingStatus, err := decodeGRPCHealthCheckResponse(body)
+	if err != nil {
+		return &health.HealthCheckResult{
+			Latency: lat,
+			Detail:  err.Error(),
+		}, nil
+	}
+	if servingStatus != grpcServingStatusServing {
+		return &health.HealthCheckResult{
+			Latency: lat,
+			Detail:  grpcServingStatusString(servingStatus),
+		}, nil
+	}
+	return &health.HealthCheckResult{
+		Latency: lat,
+		Healthy: true,
+	}, nil
+}
+
+// encodeGRPCHealthCheckRequest returns the length-prefixed message of
+// grpc.health.v1.HealthCheckRequest{service: service}.
+func encodeGRPCHealthCheckRequest(service string) []byte {
+	var msg []byte
+	if service != "" {
+		msg = append(msg, 0x0a) // field 1, length-delimited
+		msg = binary.AppendUvarint(msg, uint64(len(service)))
+		msg = append(msg, service...)
+	}
+	b := make([]byte, 5, 5+len(msg))
+	// b[0] is the compressed flag
+	binary.BigEndian.PutUint32(b[1:], uint32(len(msg)))
+	return append(b, msg...)
+}
+
+// decodeGRPCHealthCheckResponse returns the status of the
+// length-prefixed grpc.health.v1.HealthCheckResponse message.
+func decodeGRPCHealthCheckResponse(b []byte) (uint64, error) {
+	if len(b) < 5 || b[0] != 0 {
+		return 0, errInvalidGRPCResponse
+	}
+	n := binary.BigEndian.Uint32(b[1:5])
+	if uint32(len(b)-5) < n {
+		return 0, errInvalidGRPCResponse
+	}
+	msg := b[5 : 5+n]
+	var status uint64
+	for len(msg) > 0 {
+		tag, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return 0, errInvalidGRPCResponse
+		}
+		msg = msg[n:]
+		switch tag & 0x7 { // wire type
+		case 0: // varint
+			v, n := binary.Uvarint(msg)
+			if n <= 0 {
+				return 0, errInvalidGRPCResponse
+			}
+			msg = msg[n:]
+			if tag>>3 == 1 {
+				status = v
+			}
+		case 1: // fixed64
+			if len(msg) < 8 {
+				return 0, errInvalidGRPCResponse
+			}
+			msg = msg[8:]
+		case 2: // length-delimited
+			l, n := binary.Uvarint(msg)
+			if n <= 0 || uint64(len(msg)-n) < l {
+				return 0, errInvalidGRPCResponse
+			}
+			msg = msg[n+int(l):]
+		case 5: // fixed32
+			if len(msg) < 4 {
+				return 0, errInvalidGRPCResponse
+			}
+			msg = msg[4:]
+		default:
+			return 0, errInvalidGRPCResponse
+		}
+	}
+	return status, nil
+}
+
+func grpcServingStatusString(status uint64) string {
+	switch status {
+	case grpcServingStatusUnknown:
+		return "UNKNOWN"
+	case grpcServingStatusServing:
+		return "SERVING"
+	case grpcServingStatusNotServing:
+		return "NOT_SERVING"
+	case grpcServingStatusServiceUnknown:
+		return "SERVICE_UNKNOWN"
+	}
+	return fmt.Sprintf("serving status %d", status)
+}
@@ -41,10 +41,19 @@ func (mon *HTTPHealthMonitor) CheckHealth() (*health.HealthCheckResult, error) {
 	ctx, cancel := mon.ContextWithTimeout("ping request timed out")
 	defer cancel()
 
+	client := pinger
+	u := mon.url.Load()
+	if u.Scheme == "h2c" {
+		client = h2cPinger
+		h2cURL := *u
+		h2cURL.Scheme = "http"
+		u = &h2cURL
+	}
+
 	req, err := http.NewRequestWithContext(
 		ctx,
 		mon.method,
-		mon.url.Load().JoinPath(mon.config.Path).String(),
+		u.JoinPath(mon.config.Path).String(),
 		nil,
 	)
 	if err != nil {
@@ -55,7 +64,7 @@ func (mon *HTTPHealthMonitor) CheckHealth() (*health.HealthCheckResult, error) {
 	req.Header.Set("User-Agent", "GoDoxy/"+pkg.GetVersion().String())
 
 	start := time.Now()
-	resp, respErr := pinger.Do(req)
+	resp, respErr := client.Do(req)
 	if respErr == nil {
 		defer resp.Body.Close()
 	}
@@ -39,12 +39,22 @@ var ErrNegativeInterval = gperr.New("negative interval")
 
 func NewMonitor(r routes.Route) health.HealthMonCheck {
 	var mon health.HealthMonCheck
-	if r.IsAgent() {
-		mon = NewAgentProxiedMonitor(r.Agent(), r.HealthCheckConfig(), AgentTargetFromURL(&r.TargetURL().URL))
-	} else {
+	switch {
+	case r.IsAgent():
+		mon = NewAgentProxiedMonitor(r.Agent(), r.HealthCheckConfig(), AgentTargetFromURL(healthCheckURL(r)))
+	case r.TargetURL().Scheme == "unix":
+		mon = NewUnixSocketHealthMonitor(&r.TargetURL().URL, r.HealthCheckConfig())
+	default:
 		switch r := r.(type) {
+		case routes.TLSPassthroughRoute:
+			mon = NewRawHealthMonitor(healthCheckURL(r), r.HealthCheckConfig())
 		case routes.HTTPRoute:
-			mon = NewHTTPHealthMonitor(&r.TargetURL().URL, r.HealthCheckConfig())
+			switch r.TargetURL().Scheme {
+			case "grpc", "grpcs":
+				mon = NewGRPCHealthMonitor(&r.TargetURL().URL, r.HealthCheckConfig())
+			default:
+				mon = NewHTTPHealthMonitor(&r.TargetURL().URL, r.HealthCheckConfig())
+			}
 		case routes.StreamRoute:
 			mon = NewRawHealthMonitor(&r.TargetURL().URL, r.HealthCheckConfig())
 		default:
@@ -63,6 +73,19 @@ func NewMonitor(r routes.Route) health.HealthMonCheck {
 	return mon
 }
 
+// healthCheckURL returns the URL to check the health of the route.
+//
+// TLS passthrough routes are checked by TCP connect, since the TLS handshake
+// (and any client certificate it requires) is up to the client.
+func healthCheckURL(r routes.Route) *url.URL {
+	if _, ok := r.(routes.TLSPassthroughRoute); ok {
+		u := r.TargetURL().URL
+		u.Scheme = "tcp"
+		return &u
+	}
+	return &r.TargetURL().URL
+}
+
 func newMonitor(url *url.URL, config *health.HealthCheckConfig, healthCheckFunc HealthCheckFunc) *monitor {
 	mon := &monitor{
 		config:      config,
@@ -0,0 +1,65 @@
+package monitor
+
+import (
+	"net"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/yusing/go-proxy/internal/watcher/health"
+)
+
+// UnixSocketHealthMonitor checks the health of unix socket upstreams.
+//
+// The socket file must exist and accept connections,
+// since a stale socket file is left behind when the upstream crashes.
+type UnixSocketHealthMonitor struct {
+	*monitor
+	path   string
+	dialer *net.Dialer
+}
+
+func NewUnixSocketHealthMonitor(url *url.URL, config *health.HealthCheckConfig) *UnixSocketHealthMonitor {
+	mon := &UnixSocketHealthMonitor{
+		path:   url.Path,
+		dialer: &net.Dialer{Timeout: config.Timeout},
+	}
+	mon.monitor = newMonitor(url, config, mon.CheckHealth)
+	return mon
+}
+
+func (mon *UnixSocketHealthMonitor) CheckHealth() (*health.HealthCheckResult, error) {
+	start := time.Now()
+	stat, err := os.Stat(mon.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &health.HealthCheckResult{
+				Detail: err.Error(),
+			}, nil
+		}
+		return nil, err
+	}
+	if stat.Mode().Type() != os.ModeSocket {
+		return &health.HealthCheckResult{
+			Detail: mon.path + " is not a socket",
+		}, nil
+	}
+
+	ctx, cancel := mon.ContextWithTimeout("ping request timed out")
+	defer cancel()
+
+	conn, err := mon.dialer.DialContext(ctx, "unix", mon.path)
+	lat := time.Since(start)
+	if err != nil {
+		// nothing is listening on the socket
+		return &health.HealthCheckResult{
+			Latency: lat,
+			Detail:  err.Error(),
+		}, nil
+	}
+	conn.Close()
+	return &health.HealthCheckResult{
+		Healthy: true,
+		Latency: lat,
+	}, nil
+}